@@ -2,27 +2,65 @@ package root
 
 import (
 	"context"
+	"fmt"
+	"runtime"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/mazxaxz/remitly-cli/internal/config"
 	"github.com/mazxaxz/remitly-cli/internal/deploy"
+	"github.com/mazxaxz/remitly-cli/internal/exitcode"
 	"github.com/mazxaxz/remitly-cli/internal/initialize"
+	"github.com/mazxaxz/remitly-cli/internal/instances"
+	"github.com/mazxaxz/remitly-cli/internal/loadbalancer"
+	"github.com/mazxaxz/remitly-cli/internal/lock"
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+	"github.com/mazxaxz/remitly-cli/internal/output"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/internal/promote"
+	"github.com/mazxaxz/remitly-cli/internal/rollout"
+	"github.com/mazxaxz/remitly-cli/internal/sandbox"
+	"github.com/mazxaxz/remitly-cli/internal/status"
+	"github.com/mazxaxz/remitly-cli/internal/wait"
+	"github.com/mazxaxz/remitly-cli/internal/watch"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
 )
 
 const version = "1.0.0"
 
 func Execute(ctx context.Context) {
+	log.AddHook(logging.ContextHook{})
+	profile.UserAgent = fmt.Sprintf("%s/%s (%s/%s)", remitly.DefaultUserAgent, version, runtime.GOOS, runtime.GOARCH)
+
 	cmd := &cobra.Command{
 		Version: version,
 		Use:     "remitly [COMMAND]",
 		Short:   "Command Line Interface (CLI)",
 		Long:    "A simple exec created for recruitment purposes",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			return logging.Configure(cmd)
+		},
 	}
+	output.AddFlags(cmd)
+	logging.AddFlags(cmd)
+
 	// subcommands
 	cmd.AddCommand(initialize.NewCmd())
 	cmd.AddCommand(deploy.NewCmd())
+	cmd.AddCommand(promote.NewCmd())
+	cmd.AddCommand(instances.NewCmd())
+	cmd.AddCommand(loadbalancer.NewCmd())
+	cmd.AddCommand(sandbox.NewCmd())
+	cmd.AddCommand(config.NewCmd())
+	cmd.AddCommand(lock.NewCmd())
+	cmd.AddCommand(rollout.NewCmd())
+	cmd.AddCommand(status.NewCmd())
+	cmd.AddCommand(wait.NewCmd())
+	cmd.AddCommand(watch.NewCmd())
+	// help topics
+	cmd.AddCommand(exitcode.NewHelpCmd())
 
 	now := time.Now()
 	defer func() {
@@ -32,6 +70,6 @@ func Execute(ctx context.Context) {
 
 	if err := cmd.ExecuteContext(ctx); err != nil {
 		log.WithError(err).Errorln("a runtime error has occurred")
-		log.Exit(1)
+		log.Exit(exitcode.From(err))
 	}
 }
@@ -3,10 +3,11 @@ module github.com/mazxaxz/remitly-cli
 go 1.16
 
 require (
-	github.com/golang/mock v1.3.1 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
-	github.com/sirupsen/logrus v1.8.1 // indirect
+	github.com/golang/mock v1.3.1
+	github.com/pkg/errors v0.9.1
+	github.com/sirupsen/logrus v1.8.1
 	github.com/spf13/cobra v1.1.3
-	github.com/spf13/viper v1.7.1 // indirect
+	github.com/spf13/viper v1.7.1
 	github.com/stretchr/testify v1.3.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
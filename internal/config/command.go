@@ -0,0 +1,135 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/mazxaxz/remitly-cli/internal/output"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+)
+
+const (
+	version = "1.0.0"
+)
+
+type cmdContext struct {
+	context string
+	printer output.Printer
+}
+
+func NewCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "config",
+		Version: version,
+		Short:   "A subcommand for inspecting the contexts file",
+		Long: `
+A subcommand for inspecting contexts defined
+inside the contexts file.
+
+Subcommand uses:
+	'REMITLY_PATH' - created by 'remitly initialize ...' (required)
+`,
+	}
+
+	cmd.AddCommand(newValidateCmd())
+
+	return &cmd
+}
+
+func newValidateCmd() *cobra.Command {
+	var c cmdContext
+	cmd := cobra.Command{
+		Use:   "validate",
+		Short: "Validates contexts of the contexts file",
+		Long: `
+Validates contexts of the contexts file, including
+urls, TLS certificates and keys referenced by them.
+Exits with code 2 when at least one context is invalid.
+`,
+		Args:    cobra.NoArgs,
+		PreRunE: c.preRun,
+		RunE:    c.validate,
+	}
+	cmd.Flags().StringVarP(&c.context, "context", "c", "", "Validates only the context with given name (optional, default: all contexts)")
+	return &cmd
+}
+
+func (c *cmdContext) preRun(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if err := profile.LoadSettings(cmd, args); err != nil {
+		return err
+	}
+	p, err := output.NewPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	c.printer = p
+	return nil
+}
+
+func (c *cmdContext) validate(_ *cobra.Command, _ []string) error {
+	result, err := validate(viper.AllSettings(), c.context)
+	if err != nil {
+		return err
+	}
+	if err := c.printer.Print(result); err != nil {
+		return err
+	}
+	if !result.valid() {
+		return ErrInvalidConfig
+	}
+	return nil
+}
+
+// validate checks contexts of given settings, only the context with given name when specified
+func validate(source map[string]interface{}, only string) (validation, error) {
+	names, err := profile.Names(source)
+	if err != nil {
+		return nil, err
+	}
+	if only != "" {
+		names = []string{only}
+	}
+
+	result := make(validation, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		// other commands use the first context with given name only
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		cv := contextValidation{Context: name, Valid: true}
+		if err := validateContext(source, name); err != nil {
+			cv.Valid, cv.Error = false, err.Error()
+		}
+		result = append(result, cv)
+	}
+	return result, nil
+}
+
+func validateContext(source map[string]interface{}, name string) error {
+	pc, err := profile.From(source, name)
+	if errors.Is(err, profile.ErrProfileNotFound) {
+		if exists(source, name) {
+			return ErrInvalidContext
+		}
+		return ErrContextMissing
+	}
+	if err != nil {
+		return err
+	}
+	return pc.Validate()
+}
+
+func exists(source map[string]interface{}, name string) bool {
+	names, _ := profile.Names(source)
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	source := map[string]interface{}{
+		"contexts": []interface{}{
+			map[interface{}]interface{}{
+				"name": "default",
+				"http": map[interface{}]interface{}{
+					"url":      "http://cloud.remitly.io/",
+					"username": "user",
+				},
+			},
+			map[interface{}]interface{}{
+				"name": "internal",
+				"http": map[interface{}]interface{}{
+					"url":      "https://cloud.internal/",
+					"username": "user",
+				},
+				"tls": map[interface{}]interface{}{
+					"ca_file": "/does/not/exist.pem",
+				},
+			},
+			map[interface{}]interface{}{
+				"name": "broken",
+				"http": map[interface{}]interface{}{
+					"url": "http://cloud.remitly.io/",
+				},
+			},
+		},
+	}
+
+	t.Run("should validate all contexts", func(t *testing.T) {
+		// arrange
+
+		// act
+		result, err := validate(source, "")
+
+		// assert
+		assert.NoError(t, err)
+		assert.False(t, result.valid())
+		assert.Len(t, result, 3)
+		assert.Equal(t, contextValidation{Context: "default", Valid: true}, result[0])
+		assert.False(t, result[1].Valid)
+		assert.Contains(t, result[1].Error, "tls.ca_file")
+		assert.Equal(t, contextValidation{Context: "broken", Valid: false, Error: ErrInvalidContext.Error()}, result[2])
+	})
+
+	t.Run("should validate only given context", func(t *testing.T) {
+		// arrange
+
+		// act
+		result, err := validate(source, "default")
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, result.valid())
+		assert.Len(t, result, 1)
+	})
+
+	t.Run("should report missing context", func(t *testing.T) {
+		// arrange
+
+		// act
+		result, err := validate(source, "unknown")
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, validation{{Context: "unknown", Valid: false, Error: ErrContextMissing.Error()}}, result)
+	})
+}
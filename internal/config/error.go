@@ -0,0 +1,9 @@
+package config
+
+import "github.com/pkg/errors"
+
+var (
+	ErrInvalidConfig  = errors.New("contexts file contains invalid contexts")
+	ErrInvalidContext = errors.New("context has invalid settings, entry is skipped by other commands")
+	ErrContextMissing = errors.New("context does not exist")
+)
@@ -0,0 +1,30 @@
+package config
+
+type validation []contextValidation
+
+type contextValidation struct {
+	Context string `json:"context" yaml:"context"`
+	Valid   bool   `json:"valid" yaml:"valid"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (v validation) valid() bool {
+	for _, cv := range v {
+		if !cv.Valid {
+			return false
+		}
+	}
+	return true
+}
+
+func (v validation) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(v))
+	for _, cv := range v {
+		status := "valid"
+		if !cv.Valid {
+			status = "invalid"
+		}
+		rows = append(rows, []string{cv.Context, status, cv.Error})
+	}
+	return []string{"CONTEXT", "STATUS", "ERROR"}, rows
+}
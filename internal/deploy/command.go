@@ -2,15 +2,25 @@ package deploy
 
 import (
 	"context"
-	"fmt"
-	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
+	"github.com/mazxaxz/remitly-cli/internal/history"
+	"github.com/mazxaxz/remitly-cli/internal/hook"
+	"github.com/mazxaxz/remitly-cli/internal/lock"
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+	"github.com/mazxaxz/remitly-cli/internal/metrics"
+	"github.com/mazxaxz/remitly-cli/internal/output"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/internal/rollout"
+	"github.com/mazxaxz/remitly-cli/internal/tracing"
+	"github.com/mazxaxz/remitly-cli/internal/verify"
+	"github.com/mazxaxz/remitly-cli/internal/webhook"
 	"github.com/mazxaxz/remitly-cli/pkg/optional"
 	"github.com/mazxaxz/remitly-cli/pkg/remitly"
 )
@@ -23,9 +33,52 @@ type cmdContext struct {
 	app, revision string
 	count         optional.Integer
 	timeout       int
+	loadBalancer  string
+	create        createPolicy
+	printer       output.Printer
+	exporter      tracing.Exporter
+	metrics       metrics.Exporter
+	tracer        *tracing.Tracer
+	notifier      *webhook.Notifier
+	event         webhook.Event
+	hooks         hook.Hooks
+	verifier      verify.Verifier
+	lock          lock.Options
+	file          string
+	contexts      []string
+	parallel      bool
+	sequential    bool
+	halt          bool
+	// target is the context deployed into, the one selected by 'REMITLY_PROFILE' when empty
+	target string
+	// locked is set when the caller holds the lock of the application, i.e. a release
+	locked bool
+	// quiet is set when the caller prints the results, i.e. a release or a deployment into several contexts
+	quiet bool
+	// abort aborts the rollout once it returns true, i.e. after a deployment into another context has failed
+	abort func() bool
+}
+
+// Target is a deployment of the application into a context chosen by the caller, i.e. a promotion
+type Target struct {
+	Context  string
+	Revision string
+	Replicas int
 }
 
 func NewCmd() *cobra.Command {
+	cmd, _ := newCmd()
+	return cmd
+}
+
+// NewTargetCmd returns the deploy command with the function deploying the application into given target,
+// it is deployed with the flags of the command, which has parsed them already
+func NewTargetCmd() (*cobra.Command, func(cmd *cobra.Command, t Target) (Summary, error)) {
+	cmd, c := newCmd()
+	return cmd, c.deployTarget
+}
+
+func newCmd() (*cobra.Command, *cmdContext) {
 	var c cmdContext
 
 	cmd := cobra.Command{
@@ -36,13 +89,20 @@ func NewCmd() *cobra.Command {
 A subcommand for deploying specified version of 
 the application to the remote cloud.
 
+With --file several applications of a release are deployed together:
+dependencies first, independent applications concurrently. When one of
+them fails, the applications deployed already are rolled back.
+
+With --contexts the deployment is repeated in each of given contexts,
+i.e. in several regions of the cloud.
+
 Subcommand uses:
 	'REMITLY_PROFILE' - environment variable (optional, default: default)
 	'REMITLY_PATH' - created by 'remitly initialize ...' (required)
 `,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
-			if err := loadSettings(cmd, args); err != nil {
+			if err := profile.LoadSettings(cmd, args); err != nil {
 				return err
 			}
 			if err := c.scanFlags(cmd, args); err != nil {
@@ -53,55 +113,229 @@ Subcommand uses:
 		RunE: c.run,
 	}
 
-	cmd.Flags().StringVarP(&c.app, "application", "a", "", "Application name to be deployed (required)")
-	cmd.MarkFlagRequired("application")
-	cmd.Flags().StringVar(&c.revision, "revision", "", "The version of the application to to deploy (required)")
-	cmd.MarkFlagRequired("revision")
+	cmd.Flags().StringVarP(&c.app, "application", "a", "", "Application name to be deployed (required, unless --file is specified)")
+	cmd.Flags().StringVar(&c.revision, "revision", "", "The version of the application to to deploy (required, unless --file is specified)")
+	cmd.Flags().StringVarP(&c.file, "file", "f", "", "Release file with applications to be deployed together (optional)")
+	cmd.Flags().StringSliceVar(&c.contexts, "contexts", nil, "Contexts to deploy into instead of 'REMITLY_PROFILE', i.e. eu,us,apac (optional)")
+	cmd.Flags().BoolVar(&c.sequential, "sequential", false, "Deploys into --contexts one after another (optional, default)")
+	cmd.Flags().BoolVar(&c.parallel, "parallel", false, "Deploys into --contexts at once (optional)")
+	cmd.Flags().BoolVar(&c.halt, "halt-on-failure", false, "Stops deploying into --contexts after the first failure (optional)")
 
 	cmd.Flags().IntVar(&c.count.Value, "replica-count", 0, "The number of instances of this version of the app to deploy (optional, default: same as previous version)")
 	cmd.Flags().IntVarP(&c.timeout, "wait", "w", 360, "The time in seconds to wait for successful deployment (optional, default: 360)")
+	cmd.Flags().StringVar(&c.loadBalancer, "load-balancer", "", "The name of the load balancer to deploy into (optional, default: derived from --application)")
+	cmd.Flags().StringVar((*string)(&c.create), "create-load-balancer", "", "Load balancer creation policy, one of: auto|always|never (optional, default: auto)")
+	tracing.AddFlags(&cmd)
+	metrics.AddFlags(&cmd)
+	webhook.AddFlags(&cmd)
+	hook.AddFlags(&cmd)
+	verify.AddFlags(&cmd)
+	lock.AddFlags(&cmd)
 
-	return &cmd
+	return &cmd, &c
 }
 
 func (c *cmdContext) scanFlags(cmd *cobra.Command, _ []string) error {
 	c.count.Specified = cmd.Flag("replica-count").Changed
+	if c.file != "" {
+		for _, name := range []string{"application", "revision", "replica-count", "load-balancer"} {
+			if cmd.Flag(name).Changed {
+				return ErrConflictingFlags
+			}
+		}
+	} else if c.app == "" || c.revision == "" {
+		return ErrFlagsNotSpecified
+	}
+	if c.create != "" && !c.create.valid() {
+		return ErrInvalidCreatePolicy
+	}
+	if err := c.scanContexts(); err != nil {
+		return err
+	}
+	return c.scanOptions(cmd)
+}
+
+// scanOptions reads the options shared by every kind of deployment from flags of the command
+func (c *cmdContext) scanOptions(cmd *cobra.Command) error {
+	p, err := output.NewPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	c.printer = p
+	c.exporter = tracing.NewExporter(cmd)
+	c.metrics = metrics.NewExporter(cmd)
+	c.hooks = hook.FromFlags(cmd)
+	v, err := verify.FromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	c.verifier = v
+	c.lock = lock.FromFlags(cmd)
 	return nil
 }
 
 func (c *cmdContext) run(cmd *cobra.Command, _ []string) error {
-	profile := viper.GetString("PROFILE")
-	if profile == "" {
-		return ErrProfileVariableNotSet
+	c.tracer = tracing.NewTracer()
+	defer c.exportSpans(cmd.Context())
+	if len(c.contexts) > 0 {
+		return c.runContexts(cmd)
+	}
+	if c.file != "" {
+		return c.runRelease(cmd)
+	}
+	var summary Summary
+	return c.deployApp(cmd.Context(), cmd, &summary)
+}
+
+// deployTarget deploys the application into the target, summary describes the deployment once it has started
+func (c *cmdContext) deployTarget(cmd *cobra.Command, t Target) (summary Summary, err error) {
+	if c.create != "" && !c.create.valid() {
+		return summary, ErrInvalidCreatePolicy
+	}
+	if err := c.scanOptions(cmd); err != nil {
+		return summary, err
 	}
-	pc, err := profileContextFrom(viper.AllSettings(), profile)
+	d := *c
+	d.target, d.revision = t.Context, t.Revision
+	d.count = optional.Integer{Value: t.Replicas, Specified: true}
+	d.tracer = tracing.NewTracer()
+	defer d.exportSpans(cmd.Context())
+	err = d.deployApp(cmd.Context(), cmd, &summary)
+	return summary, err
+}
+
+// deployApp deploys a single application, summary describes the deployment once it has started
+func (c *cmdContext) deployApp(ctx context.Context, cmd *cobra.Command, summary *Summary) (err error) {
+	// every application in every context is deployed with its own ID, the trace ID correlates them
+	id := tracing.NewID()
+	ctx, span := c.tracer.Start(remitly.WithRequestID(ctx, id), "deployment", "app", c.app, "revision", c.revision)
+	defer func() { span.Finish(err) }()
+
+	pc, err := c.profileContext()
 	if err != nil {
 		return err
 	}
-
-	u, err := url.Parse(pc.http.url)
+	// logs of the deployment are written according to the context deployed into
+	logger, err := pc.Logger()
 	if err != nil {
-		return errors.Wrapf(err, "could not parse: '%s' url", pc.http.url)
+		return err
+	}
+	ctx = logging.WithLogger(ctx, logger)
+	registry := metrics.NewRegistry()
+	rc, err := pc.NewClient(remitly.WithObserver(observeCalls(registry)))
+	if err != nil {
+		return err
 	}
-	remitlyClient := remitly.NewClient(u, pc.http.username)
+	remitlyClient := &recorder{Clienter: rc}
 
-	timeout, cancel := context.WithTimeout(cmd.Context(), time.Duration(c.timeout)*time.Second)
+	var polls int
+	defer func() {
+		// metrics and events describe deployments which have started only
+		if summary.started.IsZero() {
+			return
+		}
+		if err == nil {
+			c.notify(ctx, webhook.Event{Type: webhook.DeploymentSucceeded})
+		} else {
+			e := webhook.Event{Type: webhook.DeploymentFailed, Error: err.Error()}
+			if summary.Code != 0 {
+				e.Code = summary.Code.String()
+			}
+			c.notify(ctx, e)
+		}
+		c.exportMetrics(ctx, registry, *summary, remitlyClient, polls, pc)
+		c.record(ctx, pc, history.KindDeployment, *summary, err)
+		// applications of a release may still be reverted, the release closes their notifiers once it has finished
+		if c.file == "" {
+			c.notifier.Close(ctx)
+		}
+	}()
+
+	timeout, cancel := context.WithTimeout(ctx, time.Duration(c.timeout)*time.Second)
 	defer cancel()
 
-	loadBalancerName := fmt.Sprintf("%s-lb", c.app)
+	loadBalancerName := c.loadBalancer
+	if loadBalancerName == "" {
+		if loadBalancerName, err = pc.LoadBalancer(c.app); err != nil {
+			return err
+		}
+	}
+	policy := c.create
+	if policy == "" {
+		policy = createPolicy(pc.CreateLoadBalancer())
+	}
+	if policy == "" {
+		policy = createAuto
+	}
+	if !policy.valid() {
+		return ErrInvalidCreatePolicy
+	}
+	span.SetAttribute("context", pc.Name())
+	span.SetAttribute("load_balancer", loadBalancerName)
+
+	var remote remitly.Locker
+	if c.lock.Remote || pc.RemoteLock() {
+		remote = lock.Remote(rc)
+	}
+	locks, err := lock.ForContext(pc, remote)
+	if err != nil {
+		return err
+	}
+	// applications of a release are locked by the release
+	if !c.locked {
+		unlock, err := locks.Acquire(ctx, c.app, id, c.lock.TTL)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	control, err := rollout.ForContext(pc)
+	if err != nil {
+		return err
+	}
+	// signals sent to previous deployments of the app do not apply to this one
+	if err := control.Clear(c.app); err != nil {
+		return err
+	}
+	defer func() {
+		if err := control.Clear(c.app); err != nil {
+			logging.FromContext(ctx).WithError(err).Warn("could not remove rollout signal")
+		}
+	}()
+
+	*summary = Summary{ID: id, TraceID: c.tracer.TraceID(), App: c.app, Revision: c.revision, LoadBalancer: loadBalancerName, started: time.Now()}
+	logging.FromContext(ctx).WithFields(log.Fields{"app": c.app, "version": c.revision}).Info("deployment started")
+	c.notifier = webhook.New(cmd, pc.Webhooks()...)
+	c.event = webhook.Event{
+		DeploymentID: summary.ID,
+		Context:      pc.Name(),
+		App:          c.app,
+		Revision:     c.revision,
+		LoadBalancer: loadBalancerName,
+	}
+	c.notify(ctx, webhook.Event{Type: webhook.DeploymentStarted})
+	c.hooks = c.hooks.WithDefaults(pc.Hooks())
 
-	original, err := snapshot(timeout, remitlyClient, loadBalancerName)
+	phaseCtx, done := c.phase(timeout, "snapshot", &summary.Durations.Snapshot)
+	original, err := snapshot(phaseCtx, remitlyClient, loadBalancerName, policy)
+	done(err)
 	if err != nil {
 		return err
 	}
+	summary.original, summary.client = original, remitlyClient
+	if len(original.instances) > 0 {
+		summary.previous = original.instances[0].Version
+	}
 
 	replicas := c.count.Value
 	if len(original.instances) == 0 {
 		if c.count.Specified && c.count.Value <= 0 {
 			if c.count.Value == 0 {
-				log.WithContext(cmd.Context()).WithField("replica-count", c.count.Value).
+				logging.FromContext(ctx).WithField("replica-count", c.count.Value).
 					Info("specified replica count is zero or negative, skipping")
-				return nil
+				summary.Code = CodeSuccess
+				return c.print(summary, remitlyClient)
 			}
 		}
 	} else {
@@ -113,41 +347,262 @@ func (c *cmdContext) run(cmd *cobra.Command, _ []string) error {
 			return ErrVersionAlreadyDeployed
 		}
 	}
+	summary.Replicas = replicas
 
-	if err := deploy(timeout, remitlyClient, loadBalancerName, c.revision, replicas); err != nil {
-		log.WithContext(cmd.Context()).WithError(err).Error("an error has occurred while deploying")
-		log.WithContext(cmd.Context()).WithField("snapshot", original).Info("rolling back...")
-		if err := rollback(cmd.Context(), remitlyClient, original); err != nil {
-			return errors.Wrap(err, "an error has occurred while rolling back")
-		}
-		log.WithContext(cmd.Context()).Info("rolling back succeeded")
+	if err := c.runHook(ctx, hook.PreDeploy, summary, original, remitlyClient); err != nil {
 		return err
 	}
 
+	phaseCtx, done = c.phase(timeout, "deploy", &summary.Durations.Deploy)
+	err = deploy(phaseCtx, remitlyClient, loadBalancerName, c.revision, replicas)
+	done(err)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("an error has occurred while deploying")
+		summary.Code = failure(phaseCtx)
+		rollbackErr := c.rollback(ctx, remitlyClient, original, summary)
+		c.postDeployFailed(ctx, summary, original, remitlyClient)
+		if rollbackErr != nil {
+			return rollbackErr
+		}
+		logging.FromContext(ctx).Info("rolling back succeeded")
+		if perr := c.print(summary, remitlyClient); perr != nil {
+			return perr
+		}
+		return rolledBack{err: summary.Code.Err(), cause: err}
+	}
+
+	phaseCtx, done = c.phase(timeout, "orchestrate", &summary.Durations.Orchestrate)
+	lookups := remitlyClient.lookups
+	g := gates{hold: func(ctx context.Context) bool {
+		return (c.abort != nil && c.abort()) || control.Hold(ctx, c.app)
+	}}
+	if c.verifier.Enabled() {
+		g.verify = func(ctx context.Context) (err error) {
+			ctx, span := c.tracer.Start(ctx, "verify")
+			defer func() { span.Finish(err) }()
+			return c.verifier.Verify(ctx, c.env(summary, original, remitlyClient))
+		}
+	}
 	result := make(chan Code)
-	go orchestrate(timeout, remitlyClient, loadBalancerName, c.revision, replicas, result)
+	go orchestrate(phaseCtx, remitlyClient, loadBalancerName, original.create, c.revision, replicas, g, result)
 	code := <-result
+	done(code.Err())
+	polls = remitlyClient.lookups - lookups
+	summary.Code = code
 
 	if code == CodeSuccess {
+		if err := c.runHook(ctx, hook.PostHealthy, summary, original, remitlyClient); err != nil {
+			return c.postHookFailed(ctx, err, summary, original, remitlyClient)
+		}
 		f := log.Fields{"app": c.app, "version": c.revision}
-		log.WithContext(cmd.Context()).WithFields(f).Info("successfully deployed application")
-		return nil
+		logging.FromContext(ctx).WithFields(f).Info("successfully deployed application")
+		if err := c.runHook(ctx, hook.PostDeploy, summary, original, remitlyClient); err != nil {
+			return c.postHookFailed(ctx, err, summary, original, remitlyClient)
+		}
+		return c.print(summary, remitlyClient)
 	}
 
 	switch code {
 	case CodeError:
-		log.WithContext(cmd.Context()).Error("an error has occurred while orchestrating")
+		logging.FromContext(ctx).Error("an error has occurred while orchestrating")
 	case CodeTimeout:
-		log.WithContext(cmd.Context()).Error("timeout exceeded")
+		logging.FromContext(ctx).Error("timeout exceeded")
 	case CodeUnhealthy:
-		log.WithContext(cmd.Context()).Error("service unhealthy")
+		logging.FromContext(ctx).Error("service unhealthy")
+	case CodeAborted:
+		logging.FromContext(ctx).Error("deployment aborted")
+	}
+
+	rollbackErr := c.rollback(ctx, remitlyClient, original, summary)
+	c.postDeployFailed(ctx, summary, original, remitlyClient)
+	if rollbackErr != nil {
+		return rollbackErr
+	}
+	if err := c.print(summary, remitlyClient); err != nil {
+		return err
+	}
+	return code.Err()
+}
+
+// postHookFailed handles a failed post-healthy or post-deploy hook of a successful deployment,
+// which is rolled back when hooks demand it
+func (c *cmdContext) postHookFailed(ctx context.Context, hookErr error, summary *Summary, original Snapshot, rc *recorder) error {
+	if !c.hooks.Rollback {
+		if err := c.print(summary, rc); err != nil {
+			return err
+		}
+		return hookErr
+	}
+	summary.Code = CodeError
+	if err := c.rollback(ctx, rc, original, summary); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("rolling back succeeded")
+	if err := c.print(summary, rc); err != nil {
+		return err
+	}
+	return rolledBack{err: ErrFailedDeployment, cause: hookErr}
+}
+
+// postDeployFailed runs post-deploy hook of a failed deployment, its failure is only logged
+func (c *cmdContext) postDeployFailed(ctx context.Context, summary *Summary, original Snapshot, rc *recorder) {
+	if err := c.runHook(ctx, hook.PostDeploy, summary, original, rc); err != nil {
+		logging.FromContext(ctx).WithError(err).Warn("post-deploy hook of a failed deployment has failed")
+	}
+}
+
+// runHook runs hook of given kind with environment variables describing the deployment
+func (c *cmdContext) runHook(ctx context.Context, kind hook.Kind, summary *Summary, original Snapshot, rc *recorder) (err error) {
+	if !c.hooks.Declared(kind) {
+		return nil
+	}
+	ctx, span := c.tracer.Start(ctx, "hook", "hook", string(kind))
+	defer func() { span.Finish(err) }()
+	return c.hooks.Run(ctx, kind, c.env(summary, original, rc))
+}
+
+// env returns environment variables describing the deployment to hooks and verification commands
+func (c *cmdContext) env(summary *Summary, original Snapshot, rc *recorder) hook.Env {
+	env := hook.Env{
+		"REMITLY_DEPLOYMENT_ID":         summary.ID,
+		"REMITLY_CONTEXT":               c.event.Context,
+		"REMITLY_APP":                   summary.App,
+		"REMITLY_REVISION":              summary.Revision,
+		"REMITLY_LOAD_BALANCER":         summary.LoadBalancer,
+		"REMITLY_REPLICAS":              strconv.Itoa(summary.Replicas),
+		"REMITLY_PREVIOUS_REVISION":     "",
+		"REMITLY_PREVIOUS_INSTANCE_IDS": "",
+		"REMITLY_CREATED_INSTANCE_IDS":  strings.Join(rc.created, ","),
+		"REMITLY_DELETED_INSTANCE_IDS":  strings.Join(rc.deleted, ","),
+		"REMITLY_ROLLED_BACK":           strconv.FormatBool(summary.RolledBack),
+		"REMITLY_CODE":                  "",
+	}
+	if len(original.instances) > 0 {
+		env["REMITLY_PREVIOUS_REVISION"] = original.instances[0].Version
+		IDs := make([]string, 0, len(original.instances))
+		for _, instance := range original.instances {
+			IDs = append(IDs, instance.ID)
+		}
+		env["REMITLY_PREVIOUS_INSTANCE_IDS"] = strings.Join(IDs, ",")
 	}
+	if summary.Code != 0 {
+		env["REMITLY_CODE"] = summary.Code.String()
+	}
+	return env
+}
+
+func (c *cmdContext) rollback(ctx context.Context, rc *recorder, original Snapshot, summary *Summary) (err error) {
+	ctx, done := c.phase(ctx, "rollback", &summary.Durations.Rollback)
+	defer func() { done(err) }()
+
+	c.notify(ctx, webhook.Event{Type: webhook.RollbackStarted})
+	if err := c.runHook(ctx, hook.PreRollback, summary, original, rc); err != nil {
+		c.notify(ctx, webhook.Event{Type: webhook.RollbackFailed, Error: err.Error()})
+		return errors.Wrapf(ErrRollbackFailed, "rollback aborted: %v", err)
+	}
+	logging.FromContext(ctx).WithField("snapshot", original).Info("rolling back...")
+	if err := rollback(ctx, rc, original); err != nil {
+		c.notify(ctx, webhook.Event{Type: webhook.RollbackFailed, Error: err.Error()})
+		return errors.Wrapf(ErrRollbackFailed, "an error has occurred while rolling back: %v", err)
+	}
+	c.notify(ctx, webhook.Event{Type: webhook.RollbackSucceeded})
+	summary.RolledBack = true
+	return nil
+}
+
+// notify posts the event completed with the details of the deployment
+func (c *cmdContext) notify(ctx context.Context, e webhook.Event) {
+	base := c.event
+	base.Type, base.Step, base.Code, base.Error = e.Type, e.Step, e.Code, e.Error
+	c.notifier.Notify(ctx, base)
+}
+
+func (c *cmdContext) exportMetrics(ctx context.Context, r *metrics.Registry, s Summary, rec *recorder, polls int, pc profile.Context) {
+	exporter := c.metrics.WithDefaults(pc.Metrics()).WithTimeout(pc.HTTPTimeout())
+	if !exporter.Enabled() {
+		return
+	}
+	collect(r, s, rec, polls, pc.Name())
+	if err := exporter.Export(ctx, r, metricsJob, "app", s.App, "context", pc.Name()); err != nil {
+		logging.FromContext(ctx).WithError(err).Warn("could not export metrics")
+	}
+}
+
+// record appends the finished deployment or its later rollback to the history of the context
+func (c *cmdContext) record(ctx context.Context, pc profile.Context, kind string, s Summary, err error) {
+	h, herr := history.ForContext(pc.Name())
+	if herr != nil {
+		logging.FromContext(ctx).WithError(herr).Warn("could not record deployment")
+		return
+	}
+	r := history.Record{
+		ID:               s.ID,
+		Kind:             kind,
+		Context:          pc.Name(),
+		App:              s.App,
+		Revision:         s.Revision,
+		PreviousRevision: s.previous,
+		LoadBalancer:     s.LoadBalancer,
+		Replicas:         s.Replicas,
+		Code:             s.Code.String(),
+		RolledBack:       s.RolledBack,
+		Owner:            lock.Owner(),
+		StartedAt:        s.started,
+		FinishedAt:       time.Now(),
+	}
+	if err != nil {
+		r.Error = err.Error()
+		if s.Code == 0 || s.Code == CodeSuccess {
+			r.Code = CodeError.String()
+		}
+	}
+	if herr := h.Append(r); herr != nil {
+		logging.FromContext(ctx).WithError(herr).Warn("could not record deployment")
+	}
+}
+
+// phase starts span of a deployment phase, the returned function finishes it
+// and stores the duration of the phase in dst
+func (c *cmdContext) phase(ctx context.Context, name string, dst *int64) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := c.tracer.Start(ctx, name)
+	return ctx, func(err error) {
+		measure(dst, start)
+		span.Finish(err)
+		if name == "rollback" {
+			// rollback has events of its own
+			return
+		}
+		e := webhook.Event{Type: webhook.DeploymentStep, Step: name}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		c.notify(ctx, e)
+	}
+}
+
+func (c *cmdContext) print(summary *Summary, r *recorder) error {
+	summary.Created, summary.Deleted = append([]string{}, r.created...), append([]string{}, r.deleted...)
+	measure(&summary.Durations.Total, summary.started)
+	if c.quiet {
+		// summaries of a release or of several contexts are printed together
+		return nil
+	}
+	return c.printer.Print(summary)
+}
+
+// profileContext returns the context deployed into
+func (c *cmdContext) profileContext() (profile.Context, error) {
+	if c.target != "" {
+		return profile.Named(c.target)
+	}
+	return profile.Current()
+}
 
-	log.WithContext(cmd.Context()).WithField("snapshot", original).Info("rolling back...")
-	if err := rollback(cmd.Context(), remitlyClient, original); err != nil {
-		return errors.Wrap(err, "an error has occurred while rolling back")
+func (c *cmdContext) exportSpans(ctx context.Context) {
+	if err := c.exporter.Export(ctx, c.tracer.Spans()); err != nil {
+		logging.FromContext(ctx).WithError(err).Warn("could not export spans")
 	}
-	return ErrFailedDeployment
 }
 
 func deploy(ctx context.Context, rc remitly.Clienter, lb, version string, replicas int) (err error) {
@@ -160,7 +615,7 @@ func deploy(ctx context.Context, rc remitly.Clienter, lb, version string, replic
 }
 
 func rollback(ctx context.Context, rc remitly.Clienter, original Snapshot) error {
-	current, err := snapshot(ctx, rc, original.loadBalancer)
+	current, err := snapshot(ctx, rc, original.loadBalancer, original.create)
 	if err != nil {
 		return err
 	}
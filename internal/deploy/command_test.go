@@ -2,13 +2,17 @@ package deploy
 
 import (
 	"context"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/mazxaxz/remitly-cli/pkg/remitly"
 	mockRemitly "github.com/mazxaxz/remitly-cli/pkg/remitly/mocks"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly/remitlytest"
 )
 
 func TestNewCmd(t *testing.T) {
@@ -23,6 +27,8 @@ func TestNewCmd(t *testing.T) {
 		assert.NotNil(t, cmd.Flag("revision"))
 		assert.NotNil(t, cmd.Flag("replica-count"))
 		assert.NotNil(t, cmd.Flag("wait"))
+		assert.NotNil(t, cmd.Flag("file"))
+		assert.NotNil(t, cmd.Flag("contexts"))
 	})
 }
 
@@ -46,6 +52,25 @@ func TestDeploy(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("should stop waiting on a hung cloud when cancelled", func(t *testing.T) {
+		// arrange
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+		srv.Inject(remitlytest.Fault{Method: http.MethodPost, Latency: time.Minute})
+		rc := srv.Client("user")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+
+		// act
+		err := deploy(ctx, rc, "lb_1", "1", 3)
+
+		// assert
+		assert.True(t, errors.Is(err, context.Canceled), err)
+		assert.True(t, time.Since(start) < 5*time.Second)
+	})
+
 	t.Run("should return error when at least instance creation fails", func(t *testing.T) {
 		// arrange
 		const loadBalancerName = "lb_1"
@@ -99,6 +124,28 @@ func TestRollback(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("should not create load balancer which has disappeared when policy forbids it", func(t *testing.T) {
+		// arrange
+		const loadBalancerName = "lb_1"
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+		give := Snapshot{
+			loadBalancer: loadBalancerName,
+			instances:    []remitly.Instance{{ID: "ins_1", Status: remitly.StateHealthy, Version: "1"}},
+			create:       createNever,
+		}
+		// expected calls
+		mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), loadBalancerName).Return(nil, remitly.ErrNotFound)
+
+		// act
+		err := rollback(context.Background(), mockRemitlyClient, give)
+
+		// assert
+		assert.Equal(t, ErrLoadBalancerNotFound, err)
+	})
+
 	t.Run("should remove newly created instances when snapshot empty", func(t *testing.T) {
 		// arrange
 		const loadBalancerName = "lb_1"
@@ -186,3 +233,17 @@ func TestRollback(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestRolledBack(t *testing.T) {
+	// arrange
+	cause := errors.Wrap(remitly.ErrForbidden, "could not create instance")
+
+	// act
+	err := error(rolledBack{err: ErrFailedDeployment, cause: cause})
+
+	// assert
+	assert.True(t, errors.Is(err, ErrFailedDeployment))
+	assert.True(t, errors.Is(err, remitly.ErrForbidden))
+	assert.False(t, errors.Is(err, ErrDeploymentTimeout))
+	assert.Equal(t, cause.Error()+": "+ErrFailedDeployment.Error(), err.Error())
+}
@@ -0,0 +1,171 @@
+package deploy
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+)
+
+// ContextState is the outcome of a deployment into one of several contexts
+type ContextState string
+
+const (
+	// ContextDeployed contexts have been deployed into successfully
+	ContextDeployed = ContextState("deployed")
+	// ContextFailed contexts have failed to deploy, deployments which had changed them have been rolled back
+	ContextFailed = ContextState("failed")
+	// ContextSkipped contexts have not been deployed into, because another one has failed
+	ContextSkipped = ContextState("skipped")
+)
+
+// Contexts is the machine-readable result of a deployment into several contexts, deployments into each context
+// have IDs of their own and the trace ID correlates them
+type Contexts struct {
+	TraceID  string          `json:"traceId" yaml:"traceId"`
+	Contexts []ContextResult `json:"contexts" yaml:"contexts"`
+}
+
+// ContextResult is the result of a deployment into a single context, holding either summary of the application
+// or result of the release. Both are missing when the deployment has not started
+type ContextResult struct {
+	Context string       `json:"context" yaml:"context"`
+	State   ContextState `json:"state" yaml:"state"`
+	Error   string       `json:"error,omitempty" yaml:"error,omitempty"`
+	Summary *Summary     `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Release *Release     `json:"release,omitempty" yaml:"release,omitempty"`
+}
+
+func (r Contexts) Table() ([]string, [][]string) {
+	header := []string{"CONTEXT", "STATE", "CODE", "ROLLED BACK", "DURATION", "ERROR"}
+	rows := make([][]string, 0, len(r.Contexts))
+	for _, ctx := range r.Contexts {
+		row := []string{ctx.Context, string(ctx.State), "", "", "", ctx.Error}
+		if s := ctx.Summary; s != nil {
+			row[2] = s.Code.String()
+			row[3] = strconv.FormatBool(s.RolledBack)
+			row[4] = (time.Duration(s.Durations.Total) * time.Millisecond).String()
+		}
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+// scanContexts validates flags of a deployment into several contexts
+func (c *cmdContext) scanContexts() error {
+	if len(c.contexts) == 0 {
+		if c.sequential || c.parallel || c.halt {
+			return ErrContextsNotSpecified
+		}
+		return nil
+	}
+	if c.sequential && c.parallel {
+		return ErrConflictingStrategies
+	}
+	seen := make(map[string]bool, len(c.contexts))
+	for _, name := range c.contexts {
+		if name == "" || seen[name] {
+			return ErrInvalidContexts
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// runContexts repeats the deployment in each of given contexts and prints the results.
+// The error of the context which has failed first is returned, as the others may have been aborted because of it
+func (c *cmdContext) runContexts(cmd *cobra.Command) error {
+	// every context is resolved and validated upfront, so a typo does not fail the deployment halfway through
+	for _, name := range c.contexts {
+		pc, err := profile.Named(name)
+		if err != nil {
+			return err
+		}
+		if err := pc.Validate(); err != nil {
+			return errors.Wrapf(err, "context '%s'", name)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		failure error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failure == nil {
+			failure = err
+		}
+	}
+	// deployments are halted after the first failure when --halt-on-failure demands it
+	halted := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return c.halt && failure != nil
+	}
+
+	results := make([]ContextResult, len(c.contexts))
+	deployTo := func(i int) {
+		results[i] = ContextResult{Context: c.contexts[i], State: ContextSkipped}
+		if halted() {
+			return
+		}
+		var err error
+		if results[i], err = c.deployTo(cmd, c.contexts[i], halted); err != nil {
+			fail(err)
+		}
+	}
+
+	logging.FromContext(cmd.Context()).WithField("contexts", c.contexts).Info("deploying into several contexts")
+	if c.parallel {
+		var wg sync.WaitGroup
+		for i := range c.contexts {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				deployTo(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range c.contexts {
+			deployTo(i)
+		}
+	}
+
+	if perr := c.printer.Print(Contexts{TraceID: c.tracer.TraceID(), Contexts: results}); perr != nil {
+		return perr
+	}
+	return failure
+}
+
+// deployTo deploys the application or the release into given context,
+// its rollout is aborted once abort returns true
+func (c *cmdContext) deployTo(cmd *cobra.Command, name string, abort func() bool) (ContextResult, error) {
+	d := *c
+	d.target, d.quiet, d.abort = name, true, abort
+	ctx, span := c.tracer.Start(cmd.Context(), "context", "context", name)
+
+	var err error
+	r := ContextResult{Context: name, State: ContextDeployed}
+	if c.file != "" {
+		r.Release, err = d.deployRelease(ctx, cmd)
+	} else {
+		var summary Summary
+		err = d.deployApp(ctx, cmd, &summary)
+		if !summary.started.IsZero() {
+			r.Summary = &summary
+		}
+	}
+	span.Finish(err)
+	if err != nil {
+		r.State, r.Error = ContextFailed, err.Error()
+		logging.FromContext(ctx).WithError(err).WithField("context", name).Error("deployment into context has failed")
+	}
+	return r, err
+}
@@ -0,0 +1,59 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanContexts(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    cmdContext
+		wantErr error
+	}{
+		{
+			name: "should accept a single context",
+			give: cmdContext{},
+		},
+		{
+			name: "should accept contexts with a strategy",
+			give: cmdContext{contexts: []string{"eu", "us"}, parallel: true, halt: true},
+		},
+		{
+			name:    "should return error when strategy is given without contexts",
+			give:    cmdContext{sequential: true},
+			wantErr: ErrContextsNotSpecified,
+		},
+		{
+			name:    "should return error when halt policy is given without contexts",
+			give:    cmdContext{halt: true},
+			wantErr: ErrContextsNotSpecified,
+		},
+		{
+			name:    "should return error when both strategies are given",
+			give:    cmdContext{contexts: []string{"eu"}, sequential: true, parallel: true},
+			wantErr: ErrConflictingStrategies,
+		},
+		{
+			name:    "should return error when context is repeated",
+			give:    cmdContext{contexts: []string{"eu", "us", "eu"}},
+			wantErr: ErrInvalidContexts,
+		},
+		{
+			name:    "should return error when context is empty",
+			give:    cmdContext{contexts: []string{"eu", ""}},
+			wantErr: ErrInvalidContexts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			err := tt.give.scanContexts()
+
+			// assert
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
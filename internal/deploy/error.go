@@ -3,11 +3,38 @@ package deploy
 import "github.com/pkg/errors"
 
 var (
-	ErrPathVariableNotSet          = errors.New("REMITLY_PATH environment variable not set")
-	ErrProfileVariableNotSet       = errors.New("REMITLY_PROFILE environment variable not set")
-	ErrInvalidContextsFileSyntax   = errors.New("invalid $REMITLY_PATH/*.yml file syntax")
-	ErrProfileNotFound             = errors.New("profile $REMITLY_PROFILE was not found inside $REMITLY_PATH/*.yml file")
 	ErrReplicaCountMustBeAboveZero = errors.New("value of --replica-count flag must be above zero")
+	ErrFlagsNotSpecified           = errors.New("either --file or both --application and --revision flags have to be specified")
+	ErrConflictingFlags            = errors.New("--file cannot be combined with --application, --revision, --replica-count and --load-balancer flags")
+	ErrInvalidRelease              = errors.New("invalid release file")
+	ErrContextsNotSpecified        = errors.New("--sequential, --parallel and --halt-on-failure flags require --contexts flag")
+	ErrConflictingStrategies       = errors.New("--sequential and --parallel flags cannot be combined")
+	ErrInvalidContexts             = errors.New("value of --contexts flag must be a list of distinct context names")
 	ErrFailedDeployment            = errors.New("deployment has failed")
 	ErrVersionAlreadyDeployed      = errors.New("given app version has been already deployed before")
+	ErrDeploymentTimeout           = errors.New("deployment has timed out")
+	ErrDeploymentUnhealthy         = errors.New("deployed instances are unhealthy")
+	ErrDeploymentAborted           = errors.New("deployment has been aborted")
+	ErrRollbackFailed              = errors.New("rollback has failed")
+	ErrInvalidCreatePolicy         = errors.New("value of --create-load-balancer flag must be one of: auto, always, never")
+	ErrLoadBalancerNotFound        = errors.New("load balancer does not exist, use --create-load-balancer=auto to create it")
+	ErrLoadBalancerAlreadyExists   = errors.New("load balancer already exists, use --create-load-balancer=auto to deploy into it")
 )
+
+// rolledBack is the error of a failed deployment which has been rolled back, it matches
+// both the error describing the failure and its cause, i.e. remitly.ErrForbidden
+type rolledBack struct {
+	err, cause error
+}
+
+func (e rolledBack) Error() string {
+	return e.cause.Error() + ": " + e.err.Error()
+}
+
+func (e rolledBack) Unwrap() error {
+	return e.cause
+}
+
+func (e rolledBack) Is(target error) bool {
+	return target == e.err
+}
@@ -0,0 +1,66 @@
+package deploy
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/mazxaxz/remitly-cli/internal/metrics"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+const metricsJob = "remitly_deploy"
+
+// observeCalls records latency of calls to the cloud by endpoint, method and status
+func observeCalls(r *metrics.Registry) func(remitly.Call) {
+	return func(call remitly.Call) {
+		status := strconv.Itoa(call.StatusCode)
+		if call.Err != nil {
+			status = "error"
+		}
+		r.Observe("remitly_api_request_duration_seconds", "Latency of calls to the cloud.", call.Duration.Seconds(),
+			"endpoint", call.Endpoint, "method", call.Method, "status", status)
+	}
+}
+
+// collect records metrics of a finished deployment described by the summary,
+// polls is the number of health checks of deployed instances, calls to the cloud are not retried
+func collect(r *metrics.Registry, s Summary, rec *recorder, polls int, context string) {
+	labels := []string{"app", s.App, "context", context}
+	with := func(extra ...string) []string {
+		return append(append([]string{}, labels...), extra...)
+	}
+
+	total := time.Duration(s.Durations.Total) * time.Millisecond
+	if total == 0 {
+		total = time.Since(s.started)
+	}
+	r.Set("remitly_deploy_duration_seconds", "Duration of the deployment.", total.Seconds(), labels...)
+	phases := map[string]int64{
+		"snapshot":    s.Durations.Snapshot,
+		"deploy":      s.Durations.Deploy,
+		"orchestrate": s.Durations.Orchestrate,
+		"rollback":    s.Durations.Rollback,
+	}
+	for phase, ms := range phases {
+		r.Set("remitly_deploy_phase_duration_seconds", "Duration of the deployment phase.",
+			(time.Duration(ms) * time.Millisecond).Seconds(), with("phase", phase)...)
+	}
+
+	r.Add("remitly_deploy_instances_created_total", "Instances created by the deployment, including rollback.", float64(len(rec.created)), labels...)
+	r.Add("remitly_deploy_instances_deleted_total", "Instances deleted by the deployment, including rollback.", float64(len(rec.deleted)), labels...)
+	r.Add("remitly_deploy_health_polls_total", "Health checks of deployed instances.", float64(polls), labels...)
+
+	for _, code := range []Code{CodeSuccess, CodeError, CodeTimeout, CodeUnhealthy, CodeAborted} {
+		r.Set("remitly_deploy_result", "Final code of the deployment, 1 for the code the deployment finished with.",
+			boolToFloat(s.Code == code), with("code", code.String())...)
+	}
+	r.Set("remitly_deploy_rolled_back", "1 when the deployment was rolled back.", boolToFloat(s.RolledBack), labels...)
+	r.Set("remitly_deploy_finished_timestamp_seconds", "Time the deployment finished at.", float64(time.Now().Unix()), labels...)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package deploy
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/internal/metrics"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+func TestCollect(t *testing.T) {
+	t.Run("should record result of the deployment and latency of calls", func(t *testing.T) {
+		// arrange
+		r := metrics.NewRegistry()
+		observe := observeCalls(r)
+		summary := Summary{
+			App:       "app_1",
+			Code:      CodeUnhealthy,
+			Durations: Durations{Total: 1500, Orchestrate: 1000},
+			started:   time.Now(),
+		}
+		rec := &recorder{created: []string{"ins_3", "ins_4"}, deleted: []string{"ins_3", "ins_4"}}
+
+		// act
+		observe(remitly.Call{Method: http.MethodGet, Endpoint: "/loadbalancers/{name}/instances", StatusCode: http.StatusOK, Duration: time.Millisecond})
+		observe(remitly.Call{Method: http.MethodPost, Endpoint: "/loadbalancers/{name}/instances", Err: errors.New("timeout")})
+		collect(r, summary, rec, 3, "production")
+
+		// assert
+		var b bytes.Buffer
+		assert.NoError(t, r.Write(&b, metrics.FormatOpenMetrics))
+		out := b.String()
+		assert.Contains(t, out, `remitly_deploy_result{app="app_1",context="production",code="unhealthy"} 1`)
+		assert.Contains(t, out, `remitly_deploy_result{app="app_1",context="production",code="success"} 0`)
+		assert.Contains(t, out, `remitly_deploy_duration_seconds{app="app_1",context="production"} 1.5`)
+		assert.Contains(t, out, `remitly_deploy_phase_duration_seconds{app="app_1",context="production",phase="orchestrate"} 1`)
+		assert.Contains(t, out, `remitly_deploy_instances_created_total{app="app_1",context="production"} 2`)
+		assert.Contains(t, out, `remitly_deploy_health_polls_total{app="app_1",context="production"} 3`)
+		assert.Contains(t, out, `remitly_api_request_duration_seconds_count{endpoint="/loadbalancers/{name}/instances",method="GET",status="200"} 1`)
+		assert.Contains(t, out, `remitly_api_request_duration_seconds_count{endpoint="/loadbalancers/{name}/instances",method="POST",status="error"} 1`)
+	})
+}
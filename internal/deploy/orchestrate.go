@@ -4,11 +4,16 @@ import (
 	"context"
 	"time"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/mazxaxz/remitly-cli/internal/logging"
 	"github.com/mazxaxz/remitly-cli/pkg/remitly"
 )
 
+// pollInterval is the time between snapshots of the load balancer
+const pollInterval = 2 * time.Second
+
 type Code int
 
 const (
@@ -16,76 +21,196 @@ const (
 	CodeError
 	CodeTimeout
 	CodeUnhealthy
+	CodeAborted
 )
 
-func orchestrate(ctx context.Context, rc remitly.Clienter, lbName, version string, replicas int, result chan Code) {
+func (c Code) String() string {
+	switch c {
+	case CodeSuccess:
+		return "success"
+	case CodeError:
+		return "error"
+	case CodeTimeout:
+		return "timeout"
+	case CodeUnhealthy:
+		return "unhealthy"
+	case CodeAborted:
+		return "aborted"
+	default:
+		return "unknown"
+	}
+}
+
+// Err returns the error describing a failed deployment with given code
+func (c Code) Err() error {
+	switch c {
+	case CodeSuccess:
+		return nil
+	case CodeTimeout:
+		return ErrDeploymentTimeout
+	case CodeUnhealthy:
+		return ErrDeploymentUnhealthy
+	case CodeAborted:
+		return ErrDeploymentAborted
+	default:
+		return ErrFailedDeployment
+	}
+}
+
+// MarshalText makes Code human readable in json and yaml outputs
+func (c Code) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// gates steer orchestration, nil gates are skipped
+type gates struct {
+	// verify is called once all new instances are healthy, old instances are removed only when it succeeds
+	verify func(context.Context) error
+	// hold is called at each pause point between steps, it blocks while the rollout is paused
+	// and returns true when the rollout was aborted
+	hold func(context.Context) bool
+}
+
+// orchestrate replaces old instances by healthy instances of the new version
+func orchestrate(ctx context.Context, rc remitly.Clienter, lbName string, policy createPolicy, version string, replicas int, g gates, result chan Code) {
 	for {
 		select {
 		case <-ctx.Done():
 			result <- CodeTimeout
 			return
-		default:
-			time.Sleep(2 * time.Second)
-			ss, err := snapshot(ctx, rc, lbName)
-			if err != nil {
-				result <- CodeError
-				return
-			}
-			if replicas <= 0 {
-				for _, ins := range ss.instances {
-					if err := rc.DeleteInstance(ctx, lbName, ins.ID); err != nil {
-						f := log.Fields{"name": lbName, "id": ins.ID}
-						log.WithContext(ctx).WithFields(f).WithError(err).Warn("could not remove instance, skipping")
-
-						result <- CodeError
-						return
-					}
+		case <-time.After(pollInterval):
+		}
+		if g.hold != nil && g.hold(ctx) {
+			result <- CodeAborted
+			return
+		}
+		ss, err := snapshot(ctx, rc, lbName, policy)
+		if err != nil {
+			result <- failure(ctx)
+			return
+		}
+		if replicas <= 0 {
+			for _, ins := range ss.instances {
+				if err := rc.DeleteInstance(ctx, lbName, ins.ID); err != nil {
+					f := log.Fields{"name": lbName, "id": ins.ID}
+					logging.FromContext(ctx).WithFields(f).WithError(err).Warn("could not remove instance, skipping")
+
+					result <- failure(ctx)
+					return
 				}
-				result <- CodeSuccess
-				return
 			}
+			result <- CodeSuccess
+			return
+		}
 
-			original := make([]string, 0)
-			deployed := make([]remitly.Instance, 0)
+		original := make([]string, 0)
+		deployed := make([]remitly.Instance, 0)
 
-			finished := true
-			for _, instance := range ss.instances {
-				if instance.Version == version {
-					deployed = append(deployed, instance)
-				} else {
-					original = append(original, instance.ID)
-					finished = false
-				}
+		finished := true
+		for _, instance := range ss.instances {
+			if instance.Version == version {
+				deployed = append(deployed, instance)
+			} else {
+				original = append(original, instance.ID)
+				finished = false
 			}
-			if finished && len(ss.instances) == replicas {
-				result <- CodeSuccess
+		}
+		if g.verify != nil {
+			if code, done := gate(ctx, rc, lbName, replicas, deployed, original, g.verify); done {
+				result <- code
 				return
 			}
+			continue
+		}
+		if finished && len(ss.instances) == replicas {
+			result <- CodeSuccess
+			return
+		}
 
-			for _, instance := range deployed {
-				switch instance.Status {
-				case remitly.StateProvisioning:
-					continue
-				case remitly.StateUnhealthy:
-					result <- CodeUnhealthy
+		for _, instance := range deployed {
+			switch instance.Status {
+			case remitly.StateProvisioning:
+				continue
+			case remitly.StateUnhealthy:
+				result <- CodeUnhealthy
+				return
+			case remitly.StateHealthy:
+				var ID string
+				if len(original) == 0 {
+					result <- CodeSuccess
 					return
-				case remitly.StateHealthy:
-					var ID string
-					if len(original) == 0 {
-						result <- CodeSuccess
-						return
-					} else if len(original) == 1 {
-						ID, original = original[0], []string{}
-					} else {
-						ID, original = original[0], original[1:]
-					}
-
-					if err := rc.DeleteInstance(ctx, lbName, ID); err != nil {
-						result <- CodeError
-						return
-					}
+				} else if len(original) == 1 {
+					ID, original = original[0], []string{}
+				} else {
+					ID, original = original[0], original[1:]
 				}
+
+				if err := rc.DeleteInstance(ctx, lbName, ID); err != nil {
+					result <- failure(ctx)
+					return
+				}
+			}
+		}
+	}
+}
+
+// gate verifies the new version once all of its instances are healthy and removes the old instances afterwards,
+// returns false while instances are provisioning
+func gate(ctx context.Context, rc remitly.Clienter, lbName string, replicas int, deployed []remitly.Instance, original []string, verify func(context.Context) error) (Code, bool) {
+	healthy := 0
+	for _, instance := range deployed {
+		switch instance.Status {
+		case remitly.StateUnhealthy:
+			return CodeUnhealthy, true
+		case remitly.StateHealthy:
+			healthy++
+		}
+	}
+	if healthy < replicas {
+		return 0, false
+	}
+
+	if err := verify(ctx); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("verification of the new version failed")
+		if ctx.Err() != nil {
+			return CodeTimeout, true
+		}
+		return CodeUnhealthy, true
+	}
+	for _, ID := range original {
+		if err := rc.DeleteInstance(ctx, lbName, ID); err != nil {
+			return failure(ctx), true
+		}
+	}
+	return CodeSuccess, true
+}
+
+// Poll passes instances of the load balancer to done at the interval orchestration polls at,
+// until done returns true or an error, or the context is done. Missing load balancer has no instances
+func Poll(ctx context.Context, rc remitly.Clienter, lbName string, done func([]remitly.Instance) (bool, error)) error {
+	for {
+		instances, err := rc.GetInstances(ctx, lbName)
+		if err != nil && !errors.Is(err, remitly.ErrNotFound) {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
+			return err
 		}
+		if finished, err := done(instances); err != nil || finished {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// failure returns the code of a call which has failed, calls aborted due to the context are timeouts
+func failure(ctx context.Context) Code {
+	if ctx.Err() != nil {
+		return CodeTimeout
 	}
+	return CodeError
 }
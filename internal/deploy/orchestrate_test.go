@@ -6,10 +6,12 @@ import (
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/mazxaxz/remitly-cli/pkg/remitly"
 	mockRemitly "github.com/mazxaxz/remitly-cli/pkg/remitly/mocks"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly/remitlytest"
 )
 
 func TestOrchestrate(t *testing.T) {
@@ -27,13 +29,42 @@ func TestOrchestrate(t *testing.T) {
 
 		// act
 		result := make(chan Code)
-		go orchestrate(ctx, mockRemitlyClient, "lb", "1", 1, result)
+		go orchestrate(ctx, mockRemitlyClient, "lb", createAuto, "1", 1, gates{}, result)
 		code := <-result
 
 		// assert
 		assert.Equal(t, CodeTimeout, code)
 	})
 
+	t.Run("should return timeout without waiting for the next poll when context is done while waiting", func(t *testing.T) {
+		t.Parallel()
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		held := false
+		g := gates{hold: func(context.Context) bool {
+			held = true
+			return false
+		}}
+
+		// expected calls
+
+		// act
+		start := time.Now()
+		result := make(chan Code)
+		go orchestrate(ctx, mockRemitlyClient, "lb", createAuto, "1", 1, g, result)
+		code := <-result
+
+		// assert
+		assert.Equal(t, CodeTimeout, code)
+		assert.True(t, time.Since(start) < pollInterval)
+		assert.False(t, held)
+	})
+
 	t.Run("should return error when snapshoting", func(t *testing.T) {
 		t.Parallel()
 		// arrange
@@ -54,7 +85,29 @@ func TestOrchestrate(t *testing.T) {
 
 		// act
 		result := make(chan Code)
-		go orchestrate(ctx, mockRemitlyClient, loadBalancerName, version, replicas, result)
+		go orchestrate(ctx, mockRemitlyClient, loadBalancerName, createAuto, version, replicas, gates{}, result)
+		code := <-result
+
+		// assert
+		assert.Equal(t, CodeError, code)
+	})
+
+	t.Run("should return error without creating load balancer which has disappeared when policy forbids it", func(t *testing.T) {
+		t.Parallel()
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		// expected calls
+		mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), "lb_1").Return(nil, remitly.ErrNotFound)
+
+		// act
+		result := make(chan Code)
+		go orchestrate(ctx, mockRemitlyClient, "lb_1", createNever, "1", 1, gates{}, result)
 		code := <-result
 
 		// assert
@@ -94,7 +147,7 @@ func TestOrchestrate(t *testing.T) {
 
 		// act
 		result := make(chan Code)
-		go orchestrate(ctx, mockRemitlyClient, loadBalancerName, version, replicas, result)
+		go orchestrate(ctx, mockRemitlyClient, loadBalancerName, createAuto, version, replicas, gates{}, result)
 		code := <-result
 
 		// assert
@@ -136,7 +189,7 @@ func TestOrchestrate(t *testing.T) {
 
 		// act
 		result := make(chan Code)
-		go orchestrate(ctx, mockRemitlyClient, loadBalancerName, version, replicas, result)
+		go orchestrate(ctx, mockRemitlyClient, loadBalancerName, createAuto, version, replicas, gates{}, result)
 		code := <-result
 
 		// assert
@@ -176,7 +229,7 @@ func TestOrchestrate(t *testing.T) {
 
 		// act
 		result := make(chan Code)
-		go orchestrate(ctx, mockRemitlyClient, loadBalancerName, version, replicas, result)
+		go orchestrate(ctx, mockRemitlyClient, loadBalancerName, createAuto, version, replicas, gates{}, result)
 		code := <-result
 
 		// assert
@@ -242,10 +295,207 @@ func TestOrchestrate(t *testing.T) {
 
 		// act
 		result := make(chan Code)
-		go orchestrate(ctx, mockRemitlyClient, loadBalancerName, version, replicas, result)
+		go orchestrate(ctx, mockRemitlyClient, loadBalancerName, createAuto, version, replicas, gates{}, result)
+		code := <-result
+
+		// assert
+		assert.Equal(t, CodeSuccess, code)
+	})
+
+	t.Run("should replace instances of a previous version within fake cloud", func(t *testing.T) {
+		t.Parallel()
+		// arrange
+		const (
+			loadBalancerName = "lb_1"
+			oldVersion       = "1"
+			version          = "2"
+			replicas         = 2
+		)
+		srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+		defer srv.Close()
+		srv.AddInstance(loadBalancerName, oldVersion)
+		srv.AddInstance(loadBalancerName, oldVersion)
+		rc := srv.Client("user")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		// act
+		err := deploy(ctx, rc, loadBalancerName, version, replicas)
+		result := make(chan Code)
+		go orchestrate(ctx, rc, loadBalancerName, createAuto, version, replicas, gates{}, result)
 		code := <-result
 
 		// assert
+		assert.NoError(t, err)
 		assert.Equal(t, CodeSuccess, code)
+		instances, _ := srv.Instances(loadBalancerName)
+		assert.Len(t, instances, replicas)
+		for _, instance := range instances {
+			assert.Equal(t, version, instance.Version)
+			assert.Equal(t, remitly.StateHealthy, instance.Status)
+		}
+	})
+
+	t.Run("should return timeout when cloud does not respond", func(t *testing.T) {
+		t.Parallel()
+		// arrange
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+		srv.Inject(remitlytest.Fault{Latency: time.Minute})
+		rc := srv.Client("user")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		start := time.Now()
+
+		// act
+		result := make(chan Code)
+		go orchestrate(ctx, rc, "lb_1", createAuto, "1", 1, gates{}, result)
+		code := <-result
+
+		// assert
+		assert.Equal(t, CodeTimeout, code)
+		assert.True(t, time.Since(start) < 10*time.Second)
+	})
+
+	t.Run("should keep old instances until verification of the new version", func(t *testing.T) {
+		t.Parallel()
+		tests := []struct {
+			name         string
+			giveVerify   error
+			wantCode     Code
+			wantVersions map[string]int
+		}{
+			{
+				name:         "should remove old instances when verification succeeds",
+				giveVerify:   nil,
+				wantCode:     CodeSuccess,
+				wantVersions: map[string]int{"2": 2},
+			},
+			{
+				name:         "should return unhealthy when verification fails",
+				giveVerify:   errors.New("smoke test failed"),
+				wantCode:     CodeUnhealthy,
+				wantVersions: map[string]int{"1": 2, "2": 2},
+			},
+		}
+
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				t.Parallel()
+				// arrange
+				const (
+					loadBalancerName = "lb_1"
+					version          = "2"
+					replicas         = 2
+				)
+				srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+				defer srv.Close()
+				srv.AddInstance(loadBalancerName, "1")
+				srv.AddInstance(loadBalancerName, "1")
+				rc := srv.Client("user")
+
+				ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+				defer cancel()
+
+				var verified map[string]int
+				verify := func(context.Context) error {
+					verified = make(map[string]int)
+					instances, _ := srv.Instances(loadBalancerName)
+					for _, instance := range instances {
+						verified[instance.Version]++
+					}
+					return tt.giveVerify
+				}
+
+				// act
+				err := deploy(ctx, rc, loadBalancerName, version, replicas)
+				result := make(chan Code)
+				go orchestrate(ctx, rc, loadBalancerName, createAuto, version, replicas, gates{verify: verify}, result)
+				code := <-result
+
+				// assert
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantCode, code)
+				assert.Equal(t, map[string]int{"1": 2, "2": 2}, verified)
+				instances, _ := srv.Instances(loadBalancerName)
+				versions := make(map[string]int)
+				for _, instance := range instances {
+					versions[instance.Version]++
+				}
+				assert.Equal(t, tt.wantVersions, versions)
+			})
+		}
+	})
+
+	t.Run("should return aborted without removing instances when rollout is aborted", func(t *testing.T) {
+		t.Parallel()
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		hold := func(context.Context) bool { return true }
+
+		// expected calls
+
+		// act
+		result := make(chan Code)
+		go orchestrate(ctx, mockRemitlyClient, "lb_1", createAuto, "2", 1, gates{hold: hold}, result)
+		code := <-result
+
+		// assert
+		assert.Equal(t, CodeAborted, code)
+	})
+}
+
+func TestPoll(t *testing.T) {
+	t.Run("should poll until instances satisfy the condition", func(t *testing.T) {
+		t.Parallel()
+		// arrange
+		clock := remitlytest.NewManualClock(time.Now())
+		srv := remitlytest.NewServer(remitlytest.WithClock(clock), remitlytest.WithBootTime(time.Minute))
+		defer srv.Close()
+		srv.AddLoadBalancer("lb_1")
+		rc := srv.Client("user")
+		_, err := rc.CreateInstance(context.Background(), "lb_1", "1")
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		polls := 0
+
+		// act
+		err = Poll(ctx, rc, "lb_1", func(instances []remitly.Instance) (bool, error) {
+			polls++
+			clock.Advance(time.Minute)
+			return instances[0].Status == remitly.StateHealthy, nil
+		})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, polls)
+	})
+
+	t.Run("should return context error when condition is not met in time", func(t *testing.T) {
+		t.Parallel()
+		// arrange
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// act
+		err := Poll(ctx, srv.Client("user"), "lb_1", func(instances []remitly.Instance) (bool, error) {
+			return len(instances) > 0, nil
+		})
+
+		// assert
+		assert.Equal(t, context.DeadlineExceeded, err)
 	})
 }
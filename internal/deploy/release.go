@@ -0,0 +1,352 @@
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mazxaxz/remitly-cli/internal/history"
+	"github.com/mazxaxz/remitly-cli/internal/lock"
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/internal/tracing"
+	"github.com/mazxaxz/remitly-cli/internal/webhook"
+	"github.com/mazxaxz/remitly-cli/pkg/optional"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+// release is a set of applications deployed together, read from the file given by --file
+type release struct {
+	Apps []releaseApp `yaml:"apps"`
+}
+
+type releaseApp struct {
+	Name         string   `yaml:"name"`
+	Revision     string   `yaml:"revision"`
+	ReplicaCount *int     `yaml:"replica_count"`
+	LoadBalancer string   `yaml:"load_balancer"`
+	DependsOn    []string `yaml:"depends_on"`
+}
+
+// AppState is the outcome of an application within a release
+type AppState string
+
+const (
+	// AppDeployed applications have been deployed successfully
+	AppDeployed = AppState("deployed")
+	// AppFailed applications have failed to deploy, their own deployment has been rolled back
+	AppFailed = AppState("failed")
+	// AppReverted applications have been deployed, then rolled back after another application has failed
+	AppReverted = AppState("reverted")
+	// AppSkipped applications have not been deployed, because another application has failed
+	AppSkipped = AppState("skipped")
+)
+
+// Release is the machine-readable result of a release
+type Release struct {
+	ID      string       `json:"id" yaml:"id"`
+	TraceID string       `json:"traceId" yaml:"traceId"`
+	Apps    []ReleaseApp `json:"apps" yaml:"apps"`
+}
+
+// ReleaseApp is the result of an application within a release, summary is missing for apps which have not started
+type ReleaseApp struct {
+	Name     string   `json:"name" yaml:"name"`
+	Revision string   `json:"revision" yaml:"revision"`
+	State    AppState `json:"state" yaml:"state"`
+	Summary  *Summary `json:"summary,omitempty" yaml:"summary,omitempty"`
+}
+
+func (r Release) Table() ([]string, [][]string) {
+	header := []string{"APP", "REVISION", "STATE", "CODE", "ROLLED BACK", "DURATION"}
+	rows := make([][]string, 0, len(r.Apps))
+	for _, app := range r.Apps {
+		row := []string{app.Name, app.Revision, string(app.State), "", "", ""}
+		if s := app.Summary; s != nil {
+			row[3] = s.Code.String()
+			row[4] = strconv.FormatBool(s.RolledBack)
+			row[5] = (time.Duration(s.Durations.Total) * time.Millisecond).String()
+		}
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+// readRelease reads and validates the release file
+func readRelease(file string) (release, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return release{}, errors.Wrapf(err, "could not read file: '%s'", file)
+	}
+	var r release
+	if err := yaml.UnmarshalStrict(b, &r); err != nil {
+		return release{}, errors.Wrapf(ErrInvalidRelease, "could not parse file '%s': %v", file, err)
+	}
+	if err := r.validate(); err != nil {
+		return release{}, err
+	}
+	return r, nil
+}
+
+func (r release) validate() error {
+	if len(r.Apps) == 0 {
+		return errors.Wrap(ErrInvalidRelease, "no apps specified")
+	}
+	names := make(map[string]bool, len(r.Apps))
+	for _, app := range r.Apps {
+		if app.Name == "" || app.Revision == "" {
+			return errors.Wrap(ErrInvalidRelease, "every app has to specify name and revision")
+		}
+		if names[app.Name] {
+			return errors.Wrapf(ErrInvalidRelease, "app '%s' is specified more than once", app.Name)
+		}
+		names[app.Name] = true
+	}
+	for _, app := range r.Apps {
+		for _, dep := range app.DependsOn {
+			if !names[dep] {
+				return errors.Wrapf(ErrInvalidRelease, "app '%s' depends on unknown app '%s'", app.Name, dep)
+			}
+		}
+	}
+
+	// apps whose dependencies can be resolved are removed until none are left, the rest forms a cycle
+	resolved := make(map[string]bool, len(r.Apps))
+	for progress := true; progress; {
+		progress = false
+		for _, app := range r.Apps {
+			if !resolved[app.Name] && dependenciesMet(app, func(dep string) bool { return resolved[dep] }) {
+				resolved[app.Name], progress = true, true
+			}
+		}
+	}
+	if len(resolved) < len(r.Apps) {
+		cycle := make([]string, 0)
+		for _, app := range r.Apps {
+			if !resolved[app.Name] {
+				cycle = append(cycle, app.Name)
+			}
+		}
+		sort.Strings(cycle)
+		return errors.Wrapf(ErrInvalidRelease, "cyclic dependency between apps: %s", strings.Join(cycle, ", "))
+	}
+	return nil
+}
+
+func dependenciesMet(app releaseApp, met func(string) bool) bool {
+	for _, dep := range app.DependsOn {
+		if !met(dep) {
+			return false
+		}
+	}
+	return true
+}
+
+// releaseFunc deploys a single application of a release,
+// the returned function rolls back the application once it has been deployed successfully
+type releaseFunc func(ctx context.Context, app releaseApp) (func(context.Context) error, error)
+
+// deploy deploys applications once all of their dependencies have been deployed, independent ones concurrently.
+// After the first failure no more applications are started, the ones which have been deployed are rolled back
+// in reverse order of deployment and the error of the failed application is returned
+func (r release) deploy(ctx context.Context, fn releaseFunc) (map[string]AppState, error) {
+	type finished struct {
+		name string
+		undo func(context.Context) error
+		err  error
+	}
+
+	var (
+		states   = make(map[string]AppState, len(r.Apps))
+		started  = make(map[string]bool, len(r.Apps))
+		done     = make(chan finished)
+		deployed []finished
+		failure  error
+		running  int
+	)
+	start := func() {
+		for _, app := range r.Apps {
+			if started[app.Name] || !dependenciesMet(app, func(dep string) bool { return states[dep] == AppDeployed }) {
+				continue
+			}
+			started[app.Name] = true
+			running++
+			go func(app releaseApp) {
+				undo, err := fn(ctx, app)
+				done <- finished{name: app.Name, undo: undo, err: err}
+			}(app)
+		}
+	}
+
+	start()
+	for running > 0 {
+		f := <-done
+		running--
+		if f.err != nil {
+			states[f.name] = AppFailed
+			if failure == nil {
+				failure = f.err
+			}
+			continue
+		}
+		states[f.name] = AppDeployed
+		deployed = append(deployed, f)
+		if failure == nil {
+			start()
+		}
+	}
+	for _, app := range r.Apps {
+		if !started[app.Name] {
+			states[app.Name] = AppSkipped
+		}
+	}
+	if failure == nil {
+		return states, nil
+	}
+
+	var rollbackErr error
+	for i := len(deployed) - 1; i >= 0; i-- {
+		if err := deployed[i].undo(ctx); err != nil {
+			logging.FromContext(ctx).WithError(err).WithField("app", deployed[i].name).Error("could not roll back deployed application")
+			if rollbackErr == nil {
+				rollbackErr = err
+			}
+			continue
+		}
+		states[deployed[i].name] = AppReverted
+	}
+	if rollbackErr != nil {
+		return states, rollbackErr
+	}
+	return states, failure
+}
+
+// runRelease deploys applications of the release file and prints the result
+func (c *cmdContext) runRelease(cmd *cobra.Command) error {
+	result, err := c.deployRelease(cmd.Context(), cmd)
+	if result == nil {
+		return err
+	}
+	if perr := c.printer.Print(result); perr != nil {
+		return perr
+	}
+	return err
+}
+
+// deployRelease deploys applications of the release file, the result is missing when none of them has started
+func (c *cmdContext) deployRelease(ctx context.Context, cmd *cobra.Command) (_ *Release, err error) {
+	r, err := readRelease(c.file)
+	if err != nil {
+		return nil, err
+	}
+	// the release has an ID of its own, it holds locks of its applications, each of them is deployed with its own ID
+	id := tracing.NewID()
+	ctx, span := c.tracer.Start(remitly.WithRequestID(ctx, id), "release", "file", c.file)
+	defer func() { span.Finish(err) }()
+
+	pc, err := c.profileContext()
+	if err != nil {
+		return nil, err
+	}
+	// logs of the deployment are written according to the context deployed into
+	logger, err := pc.Logger()
+	if err != nil {
+		return nil, err
+	}
+	ctx = logging.WithLogger(ctx, logger)
+	rc, err := pc.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	var remote remitly.Locker
+	if c.lock.Remote || pc.RemoteLock() {
+		remote = lock.Remote(rc)
+	}
+	locks, err := lock.ForContext(pc, remote)
+	if err != nil {
+		return nil, err
+	}
+	// all apps are locked upfront, so the release does not fail halfway through on a lock
+	for _, app := range r.Apps {
+		unlock, err := locks.Acquire(ctx, app.Name, id, c.lock.TTL)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	var (
+		mu        sync.Mutex
+		summaries = make(map[string]*Summary, len(r.Apps))
+		notifiers []*webhook.Notifier
+	)
+	defer func() {
+		var wg sync.WaitGroup
+		for _, n := range notifiers {
+			wg.Add(1)
+			go func(n *webhook.Notifier) {
+				defer wg.Done()
+				n.Close(ctx)
+			}(n)
+		}
+		wg.Wait()
+	}()
+	logging.FromContext(ctx).WithFields(log.Fields{"file": c.file, "context": pc.Name(), "apps": len(r.Apps)}).Info("release started")
+	states, err := r.deploy(ctx, func(ctx context.Context, app releaseApp) (func(context.Context) error, error) {
+		d := c.forApp(app)
+		summary := &Summary{}
+		mu.Lock()
+		summaries[app.Name] = summary
+		mu.Unlock()
+		err := d.deployApp(ctx, cmd, summary)
+		mu.Lock()
+		notifiers = append(notifiers, d.notifier)
+		mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) error { return d.revert(ctx, pc, summary) }, nil
+	})
+	if err == nil {
+		logging.FromContext(ctx).WithField("file", c.file).Info("successfully released applications")
+	}
+
+	result := Release{ID: id, TraceID: c.tracer.TraceID()}
+	for _, app := range r.Apps {
+		a := ReleaseApp{Name: app.Name, Revision: app.Revision, State: states[app.Name]}
+		if s := summaries[app.Name]; s != nil && !s.started.IsZero() {
+			a.Summary = s
+		}
+		result.Apps = append(result.Apps, a)
+	}
+	return &result, err
+}
+
+// forApp returns a copy of the context deploying given application of the release
+func (c *cmdContext) forApp(app releaseApp) *cmdContext {
+	d := *c
+	d.app, d.revision, d.loadBalancer = app.Name, app.Revision, app.LoadBalancer
+	d.count = optional.Integer{}
+	if app.ReplicaCount != nil {
+		d.count = optional.Integer{Value: *app.ReplicaCount, Specified: true}
+	}
+	d.locked, d.quiet = true, true
+	return &d
+}
+
+// revert rolls back an application of the release which has been deployed, after another one has failed
+func (c *cmdContext) revert(ctx context.Context, pc profile.Context, summary *Summary) error {
+	logging.FromContext(ctx).WithFields(log.Fields{"app": c.app, "version": c.revision}).Info("rolling back deployed application")
+	err := c.rollback(ctx, summary.client, summary.original, summary)
+	c.record(ctx, pc, history.KindRollback, *summary, err)
+	return err
+}
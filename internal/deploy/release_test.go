@@ -0,0 +1,206 @@
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRelease(t *testing.T) {
+	tests := []struct {
+		name        string
+		giveFile    string
+		wantRelease release
+		wantErr     string
+	}{
+		{
+			name: "should read apps with their dependencies",
+			giveFile: `
+apps:
+  - name: api
+    revision: 2.0.0
+    replica_count: 3
+    depends_on: [db]
+  - name: db
+    revision: 1.1.0
+    load_balancer: db-primary
+`,
+			wantRelease: release{Apps: []releaseApp{
+				{Name: "api", Revision: "2.0.0", ReplicaCount: func() *int { n := 3; return &n }(), DependsOn: []string{"db"}},
+				{Name: "db", Revision: "1.1.0", LoadBalancer: "db-primary"},
+			}},
+		},
+		{
+			name:     "should return error when no apps are specified",
+			giveFile: "apps: []",
+			wantErr:  "no apps specified: invalid release file",
+		},
+		{
+			name:     "should return error on unknown keys",
+			giveFile: "apps:\n  - name: api\n    revision: 1\n    dependson: [db]\n",
+			wantErr:  "field dependson not found",
+		},
+		{
+			name:     "should return error when revision is missing",
+			giveFile: "apps:\n  - name: api\n",
+			wantErr:  "every app has to specify name and revision: invalid release file",
+		},
+		{
+			name:     "should return error when app is duplicated",
+			giveFile: "apps:\n  - {name: api, revision: 1}\n  - {name: api, revision: 2}\n",
+			wantErr:  "app 'api' is specified more than once: invalid release file",
+		},
+		{
+			name:     "should return error when dependency is unknown",
+			giveFile: "apps:\n  - {name: api, revision: 1, depends_on: [db]}\n",
+			wantErr:  "app 'api' depends on unknown app 'db': invalid release file",
+		},
+		{
+			name: "should return error on cyclic dependencies",
+			giveFile: "apps:\n  - {name: api, revision: 1, depends_on: [web]}\n" +
+				"  - {name: web, revision: 1, depends_on: [api]}\n  - {name: db, revision: 1}\n",
+			wantErr: "cyclic dependency between apps: api, web: invalid release file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			file := filepath.Join(t.TempDir(), "release.yml")
+			assert.NoError(t, ioutil.WriteFile(file, []byte(tt.giveFile), 0644))
+
+			// act
+			r, err := readRelease(file)
+
+			// assert
+			if tt.wantErr != "" {
+				assert.True(t, errors.Is(err, ErrInvalidRelease), err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantRelease, r)
+		})
+	}
+}
+
+// journal records calls of a release in order
+type journal struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (j *journal) add(call string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.calls = append(j.calls, call)
+}
+
+// fn returns releaseFunc failing for given apps, app 'slow' takes longer than the others
+func (j *journal) fn(failing ...string) releaseFunc {
+	return func(ctx context.Context, app releaseApp) (func(context.Context) error, error) {
+		if app.Name == "slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		j.add("deploy " + app.Name)
+		for _, name := range failing {
+			if app.Name == name {
+				return nil, ErrDeploymentUnhealthy
+			}
+		}
+		return func(context.Context) error {
+			j.add("rollback " + app.Name)
+			return nil
+		}, nil
+	}
+}
+
+func TestReleaseDeploy(t *testing.T) {
+	t.Run("should deploy apps after their dependencies", func(t *testing.T) {
+		// arrange
+		r := release{Apps: []releaseApp{
+			{Name: "web", DependsOn: []string{"api"}},
+			{Name: "api", DependsOn: []string{"db", "slow"}},
+			{Name: "slow"},
+			{Name: "db"},
+		}}
+		var j journal
+
+		// act
+		states, err := r.deploy(context.Background(), j.fn())
+
+		// assert
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"deploy db", "deploy slow"}, j.calls[:2])
+		assert.Equal(t, []string{"deploy api", "deploy web"}, j.calls[2:])
+		assert.Equal(t, map[string]AppState{"web": AppDeployed, "api": AppDeployed, "slow": AppDeployed, "db": AppDeployed}, states)
+	})
+
+	t.Run("should deploy independent apps concurrently", func(t *testing.T) {
+		// arrange
+		r := release{Apps: []releaseApp{{Name: "a"}, {Name: "b"}}}
+		var wg sync.WaitGroup
+		wg.Add(2)
+		fn := func(ctx context.Context, app releaseApp) (func(context.Context) error, error) {
+			wg.Done()
+			// both apps have to be running at once for the wait to finish
+			wg.Wait()
+			return nil, nil
+		}
+
+		// act
+		_, err := r.deploy(context.Background(), fn)
+
+		// assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("should roll back deployed apps in reverse order and skip the rest when an app fails", func(t *testing.T) {
+		// arrange
+		r := release{Apps: []releaseApp{
+			{Name: "db"},
+			{Name: "api", DependsOn: []string{"db"}},
+			{Name: "worker", DependsOn: []string{"api"}},
+			{Name: "slow", DependsOn: []string{"db"}},
+			{Name: "web", DependsOn: []string{"worker"}},
+		}}
+		var j journal
+
+		// act
+		states, err := r.deploy(context.Background(), j.fn("worker"))
+
+		// assert
+		assert.Equal(t, ErrDeploymentUnhealthy, err)
+		assert.Equal(t, []string{
+			"deploy db", "deploy api", "deploy worker", "deploy slow",
+			"rollback slow", "rollback api", "rollback db",
+		}, j.calls)
+		assert.Equal(t, map[string]AppState{
+			"db": AppReverted, "api": AppReverted, "worker": AppFailed, "slow": AppReverted, "web": AppSkipped,
+		}, states)
+	})
+
+	t.Run("should return rollback error when a deployed app cannot be rolled back", func(t *testing.T) {
+		// arrange
+		r := release{Apps: []releaseApp{{Name: "db"}, {Name: "api", DependsOn: []string{"db"}}}}
+		fn := func(ctx context.Context, app releaseApp) (func(context.Context) error, error) {
+			if app.Name == "api" {
+				return nil, ErrFailedDeployment
+			}
+			return func(context.Context) error { return ErrRollbackFailed }, nil
+		}
+
+		// act
+		states, err := r.deploy(context.Background(), fn)
+
+		// assert
+		assert.Equal(t, ErrRollbackFailed, err)
+		assert.Equal(t, map[string]AppState{"db": AppDeployed, "api": AppFailed}, states)
+	})
+}
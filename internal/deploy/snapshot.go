@@ -3,33 +3,66 @@ package deploy
 import (
 	"context"
 
-	log "github.com/sirupsen/logrus"
-
+	"github.com/mazxaxz/remitly-cli/internal/logging"
 	"github.com/mazxaxz/remitly-cli/pkg/remitly"
 )
 
+type createPolicy string
+
+const (
+	// createAuto creates the load balancer when it does not exist
+	createAuto = createPolicy("auto")
+	// createAlways creates the load balancer, fails when it already exists
+	createAlways = createPolicy("always")
+	// createNever fails when the load balancer does not exist
+	createNever = createPolicy("never")
+)
+
+func (p createPolicy) valid() bool {
+	return p == createAuto || p == createAlways || p == createNever
+}
+
+// resnapshot returns the policy of snapshots taken after the first one, the load balancer
+// which disappears during the deployment is created again unless the policy forbids it
+func (p createPolicy) resnapshot() createPolicy {
+	if p == createNever {
+		return createNever
+	}
+	return createAuto
+}
+
 type Snapshot struct {
 	loadBalancer string
 	instances    []remitly.Instance
+	// create is the policy of snapshots taken later, i.e. while orchestrating or rolling back
+	create createPolicy
 }
 
-func snapshot(ctx context.Context, rc remitly.Clienter, lb string) (Snapshot, error) {
+func snapshot(ctx context.Context, rc remitly.Clienter, lb string, policy createPolicy) (Snapshot, error) {
 	instances, err := rc.GetInstances(ctx, lb)
+	if err == nil && policy == createAlways {
+		logging.FromContext(ctx).WithField("name", lb).Error("load balancer already exists")
+		return Snapshot{}, ErrLoadBalancerAlreadyExists
+	}
 	if err != nil {
 		switch err {
 		case remitly.ErrNotFound:
-			log.WithContext(ctx).WithField("name", lb).Info("load balancer not found, creating right now...")
+			if policy == createNever {
+				logging.FromContext(ctx).WithField("name", lb).Error("load balancer not found")
+				return Snapshot{}, ErrLoadBalancerNotFound
+			}
+			logging.FromContext(ctx).WithField("name", lb).Info("load balancer not found, creating right now...")
 			if _, err := rc.CreateLoadBalancer(ctx, lb); err != nil {
-				log.WithContext(ctx).WithField("name", lb).WithError(err).Error("could not create load balancer")
+				logging.FromContext(ctx).WithField("name", lb).WithError(err).Error("could not create load balancer")
 				return Snapshot{}, err
 			}
-			log.WithContext(ctx).WithField("name", lb).Info("load balancer successfully created")
+			logging.FromContext(ctx).WithField("name", lb).Info("load balancer successfully created")
 		default:
-			log.WithContext(ctx).WithField("name", lb).WithError(err).Error("could not get load balancer instances")
+			logging.FromContext(ctx).WithField("name", lb).WithError(err).Error("could not get load balancer instances")
 			return Snapshot{}, err
 		}
 	}
 
-	s := Snapshot{loadBalancer: lb, instances: instances}
+	s := Snapshot{loadBalancer: lb, instances: instances, create: policy.resnapshot()}
 	return s, nil
 }
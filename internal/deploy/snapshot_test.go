@@ -24,7 +24,7 @@ func TestSnapshot(t *testing.T) {
 		mockRemitlyClient.EXPECT().CreateLoadBalancer(gomock.Any(), loadBalancerName).Return(remitly.LoadBalancer{}, nil)
 
 		// act
-		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName)
+		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName, createAuto)
 
 		// assert
 		assert.NoError(t, err)
@@ -44,7 +44,7 @@ func TestSnapshot(t *testing.T) {
 		mockRemitlyClient.EXPECT().CreateLoadBalancer(gomock.Any(), loadBalancerName).Return(remitly.LoadBalancer{}, remitly.ErrUnknown)
 
 		// act
-		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName)
+		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName, createAuto)
 
 		// assert
 		assert.Error(t, err, remitly.ErrUnknown)
@@ -62,7 +62,7 @@ func TestSnapshot(t *testing.T) {
 		mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), loadBalancerName).Return(nil, remitly.ErrUnknown)
 
 		// act
-		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName)
+		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName, createAuto)
 
 		// assert
 		assert.Error(t, err, remitly.ErrUnknown)
@@ -85,11 +85,85 @@ func TestSnapshot(t *testing.T) {
 		mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), loadBalancerName).Return(instances, nil)
 
 		// act
-		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName)
+		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName, createAuto)
 
 		// assert
 		assert.NoError(t, err)
 		assert.Equal(t, loadBalancerName, result.loadBalancer)
 		assert.Equal(t, instances, result.instances)
 	})
+
+	t.Run("should return error when load balancer does not exist and creation is disabled", func(t *testing.T) {
+		// arrange
+		const loadBalancerName = "lb_1"
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+		// expected calls
+		mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), loadBalancerName).Return(nil, remitly.ErrNotFound)
+
+		// act
+		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName, createNever)
+
+		// assert
+		assert.Equal(t, ErrLoadBalancerNotFound, err)
+		assert.Equal(t, Snapshot{}, result)
+	})
+
+	t.Run("should return error when load balancer already exists and creation is forced", func(t *testing.T) {
+		// arrange
+		const loadBalancerName = "lb_1"
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+		// expected calls
+		mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), loadBalancerName).Return([]remitly.Instance{}, nil)
+
+		// act
+		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName, createAlways)
+
+		// assert
+		assert.Equal(t, ErrLoadBalancerAlreadyExists, err)
+		assert.Equal(t, Snapshot{}, result)
+	})
+
+	t.Run("should create load balancer when it does not exist and creation is forced", func(t *testing.T) {
+		// arrange
+		const loadBalancerName = "lb_1"
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+		// expected calls
+		mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), loadBalancerName).Return(nil, remitly.ErrNotFound)
+		mockRemitlyClient.EXPECT().CreateLoadBalancer(gomock.Any(), loadBalancerName).Return(remitly.LoadBalancer{}, nil)
+
+		// act
+		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName, createAlways)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, loadBalancerName, result.loadBalancer)
+		assert.Equal(t, createAuto, result.create)
+	})
+
+	t.Run("should forbid creation in later snapshots when policy forbids it", func(t *testing.T) {
+		// arrange
+		const loadBalancerName = "lb_1"
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+		// expected calls
+		mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), loadBalancerName).Return([]remitly.Instance{}, nil)
+
+		// act
+		result, err := snapshot(context.Background(), mockRemitlyClient, loadBalancerName, createNever)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, createNever, result.create)
+	})
 }
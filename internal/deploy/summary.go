@@ -0,0 +1,92 @@
+package deploy
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+// Summary is the machine-readable result of a single deployment
+type Summary struct {
+	ID           string    `json:"id" yaml:"id"`
+	TraceID      string    `json:"traceId" yaml:"traceId"`
+	App          string    `json:"app" yaml:"app"`
+	Revision     string    `json:"revision" yaml:"revision"`
+	LoadBalancer string    `json:"loadBalancer" yaml:"loadBalancer"`
+	Replicas     int       `json:"replicas" yaml:"replicas"`
+	Code         Code      `json:"code" yaml:"code"`
+	RolledBack   bool      `json:"rolledBack" yaml:"rolledBack"`
+	Created      []string  `json:"created" yaml:"created"`
+	Deleted      []string  `json:"deleted" yaml:"deleted"`
+	Durations    Durations `json:"durations" yaml:"durations"`
+
+	started  time.Time
+	previous string
+	// original and client roll the deployment back after it has finished
+	original Snapshot
+	client   *recorder
+}
+
+// Durations of the deployment phases, in milliseconds
+type Durations struct {
+	Total       int64 `json:"totalMilliseconds" yaml:"totalMilliseconds"`
+	Snapshot    int64 `json:"snapshotMilliseconds" yaml:"snapshotMilliseconds"`
+	Deploy      int64 `json:"deployMilliseconds" yaml:"deployMilliseconds"`
+	Orchestrate int64 `json:"orchestrateMilliseconds" yaml:"orchestrateMilliseconds"`
+	Rollback    int64 `json:"rollbackMilliseconds" yaml:"rollbackMilliseconds"`
+}
+
+func (s Summary) Table() ([]string, [][]string) {
+	rows := [][]string{
+		{"ID", s.ID},
+		{"TRACE ID", s.TraceID},
+		{"APP", s.App},
+		{"REVISION", s.Revision},
+		{"LOAD BALANCER", s.LoadBalancer},
+		{"REPLICAS", strconv.Itoa(s.Replicas)},
+		{"CODE", s.Code.String()},
+		{"ROLLED BACK", strconv.FormatBool(s.RolledBack)},
+		{"CREATED", strings.Join(s.Created, ",")},
+		{"DELETED", strings.Join(s.Deleted, ",")},
+		{"DURATION", (time.Duration(s.Durations.Total) * time.Millisecond).String()},
+	}
+	return nil, rows
+}
+
+// measure stores the time elapsed since start in dst
+func measure(dst *int64, start time.Time) {
+	*dst = time.Since(start).Milliseconds()
+}
+
+// recorder keeps track of the instances created and deleted through the wrapped client
+// and of the number of instance lookups
+type recorder struct {
+	remitly.Clienter
+	created []string
+	deleted []string
+	lookups int
+}
+
+func (r *recorder) GetInstances(ctx context.Context, lbName string) ([]remitly.Instance, error) {
+	r.lookups++
+	return r.Clienter.GetInstances(ctx, lbName)
+}
+
+func (r *recorder) CreateInstance(ctx context.Context, lbName, version string) (remitly.Instance, error) {
+	instance, err := r.Clienter.CreateInstance(ctx, lbName, version)
+	if err == nil {
+		r.created = append(r.created, instance.ID)
+	}
+	return instance, err
+}
+
+func (r *recorder) DeleteInstance(ctx context.Context, lbName, ID string) error {
+	err := r.Clienter.DeleteInstance(ctx, lbName, ID)
+	if err == nil {
+		r.deleted = append(r.deleted, ID)
+	}
+	return err
+}
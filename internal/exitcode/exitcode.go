@@ -0,0 +1,188 @@
+package exitcode
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/mazxaxz/remitly-cli/internal/config"
+	"github.com/mazxaxz/remitly-cli/internal/deploy"
+	"github.com/mazxaxz/remitly-cli/internal/hook"
+	"github.com/mazxaxz/remitly-cli/internal/initialize"
+	"github.com/mazxaxz/remitly-cli/internal/instances"
+	"github.com/mazxaxz/remitly-cli/internal/loadbalancer"
+	"github.com/mazxaxz/remitly-cli/internal/lock"
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+	"github.com/mazxaxz/remitly-cli/internal/output"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/internal/promote"
+	"github.com/mazxaxz/remitly-cli/internal/sandbox"
+	"github.com/mazxaxz/remitly-cli/internal/verify"
+	"github.com/mazxaxz/remitly-cli/internal/wait"
+	"github.com/mazxaxz/remitly-cli/internal/watch"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+// Exit codes are part of the public interface of the CLI, once released they must not change
+const (
+	Success          = 0
+	Error            = 1
+	Config           = 2
+	Auth             = 3
+	AlreadyDeployed  = 4
+	HookFailed       = 5
+	Locked           = 6
+	WaitTimeout      = 7
+	WaitUnhealthy    = 8
+	NothingToPromote = 9
+	RolledBack       = 10
+	Timeout          = 11
+	Unhealthy        = 12
+	Aborted          = 13
+	NotFound         = 14
+	Conflict         = 15
+	RollbackFailed   = 20
+)
+
+type entry struct {
+	code        int
+	description string
+	errs        []error
+}
+
+// table is ordered by precedence, the first entry matching the error wins
+var table = []entry{
+	{code: Success, description: "command succeeded"},
+	{code: RollbackFailed, description: "deployment failed and rollback failed as well, manual intervention is needed", errs: []error{
+		deploy.ErrRollbackFailed,
+	}},
+	{code: Timeout, description: "deployment timed out, rollback succeeded", errs: []error{
+		deploy.ErrDeploymentTimeout,
+	}},
+	{code: Unhealthy, description: "deployed instances were unhealthy, rollback succeeded", errs: []error{
+		deploy.ErrDeploymentUnhealthy,
+	}},
+	{code: Aborted, description: "deployment aborted by 'remitly rollout abort' or --halt-on-failure, rollback succeeded", errs: []error{
+		deploy.ErrDeploymentAborted,
+	}},
+	// rejected credentials take precedence over the rollback they have caused
+	{code: Auth, description: "cloud rejected the credentials of the context", errs: []error{
+		remitly.ErrForbidden,
+	}},
+	{code: RolledBack, description: "deployment failed, rollback succeeded", errs: []error{
+		deploy.ErrFailedDeployment,
+	}},
+	{code: AlreadyDeployed, description: "given revision has been already deployed", errs: []error{
+		deploy.ErrVersionAlreadyDeployed,
+	}},
+	{code: HookFailed, description: "lifecycle hook failed, deployment was aborted or left in place", errs: []error{
+		hook.ErrHookFailed,
+	}},
+	{code: Locked, description: "application is locked by another deployment", errs: []error{
+		lock.ErrLocked,
+		lock.ErrNotOwner,
+	}},
+	{code: WaitTimeout, description: "condition of 'remitly wait' has not been met before the timeout", errs: []error{
+		wait.ErrConditionNotMet,
+	}},
+	{code: WaitUnhealthy, description: "instances awaited by 'remitly wait' are unhealthy", errs: []error{
+		wait.ErrInstancesUnhealthy,
+	}},
+	{code: NothingToPromote, description: "source context of 'remitly promote' has no single healthy revision", errs: []error{
+		promote.ErrNoHealthyRevision,
+		promote.ErrRolloutInProgress,
+	}},
+	{code: NotFound, description: "resource the command operates on does not exist, i.e. instance or load balancer", errs: []error{
+		instances.ErrInstanceNotFound,
+		deploy.ErrLoadBalancerNotFound,
+		remitly.ErrNotFound,
+	}},
+	{code: Conflict, description: "state of the resource conflicts with the command, i.e. load balancer still has instances or already exists", errs: []error{
+		loadbalancer.ErrLoadBalancerNotEmpty,
+		deploy.ErrLoadBalancerAlreadyExists,
+	}},
+	{code: Config, description: "invalid configuration, environment variables, contexts file or flags", errs: []error{
+		profile.ErrPathVariableNotSet,
+		profile.ErrProfileVariableNotSet,
+		profile.ErrInvalidContextsFileSyntax,
+		profile.ErrProfileNotFound,
+		profile.ErrInvalidCAFile,
+		profile.ErrIncompleteClientCertificate,
+		profile.ErrInvalidURL,
+		profile.ErrTLSRequiresHTTPS,
+		profile.ErrInvalidProxyURL,
+		config.ErrInvalidConfig,
+		deploy.ErrReplicaCountMustBeAboveZero,
+		deploy.ErrFlagsNotSpecified,
+		deploy.ErrConflictingFlags,
+		deploy.ErrInvalidRelease,
+		deploy.ErrContextsNotSpecified,
+		deploy.ErrConflictingStrategies,
+		deploy.ErrInvalidContexts,
+		promote.ErrSameContext,
+		promote.ErrConflictingFlags,
+		deploy.ErrInvalidCreatePolicy,
+		wait.ErrInvalidCondition,
+		watch.ErrInvalidInterval,
+		verify.ErrInvalidBodyPattern,
+		verify.ErrInvalidAttempts,
+		initialize.ErrFlagsNotSpecified,
+		loadbalancer.ErrNameNotSpecified,
+		sandbox.ErrInvalidFailureRate,
+		sandbox.ErrInvalidContextsFile,
+		logging.ErrInvalidLevel,
+		logging.ErrInvalidFormat,
+		output.ErrUnsupportedFormat,
+		output.ErrTemplateNotSpecified,
+	}},
+	{code: Error, description: "any other error"},
+}
+
+// From returns the exit code for given error
+func From(err error) int {
+	if err == nil {
+		return Success
+	}
+	for _, e := range table {
+		for _, target := range e.errs {
+			if errors.Is(err, target) {
+				return e.code
+			}
+		}
+	}
+	var notFound viper.ConfigFileNotFoundError
+	if errors.As(err, &notFound) {
+		return Config
+	}
+	return Error
+}
+
+// NewHelpCmd returns 'exit-codes' help topic, available through 'remitly help exit-codes'
+func NewHelpCmd() *cobra.Command {
+	sorted := make([]entry, len(table))
+	copy(sorted, table)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].code < sorted[j].code })
+
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "CODE\tDESCRIPTION")
+	for _, e := range sorted {
+		fmt.Fprintf(tw, "%d\t%s\n", e.code, e.description)
+	}
+	_ = tw.Flush()
+
+	return &cobra.Command{
+		Use:   "exit-codes",
+		Short: "Exit codes returned by the CLI",
+		Long: fmt.Sprintf(`
+Exit codes returned by the CLI, scripts can rely on them
+to distinguish failure classes.
+
+%s`, b.String()),
+	}
+}
@@ -0,0 +1,129 @@
+package exitcode
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/internal/deploy"
+	"github.com/mazxaxz/remitly-cli/internal/hook"
+	"github.com/mazxaxz/remitly-cli/internal/instances"
+	"github.com/mazxaxz/remitly-cli/internal/loadbalancer"
+	"github.com/mazxaxz/remitly-cli/internal/lock"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/internal/promote"
+	"github.com/mazxaxz/remitly-cli/internal/wait"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		giveErr  error
+		wantCode int
+	}{
+		{
+			name:     "should return success when no error",
+			giveErr:  nil,
+			wantCode: Success,
+		},
+		{
+			name:     "should return rollback failed when wrapped",
+			giveErr:  errors.Wrapf(deploy.ErrRollbackFailed, "an error has occurred while rolling back: %v", remitly.ErrForbidden),
+			wantCode: RollbackFailed,
+		},
+		{
+			name:     "should return timeout",
+			giveErr:  deploy.CodeTimeout.Err(),
+			wantCode: Timeout,
+		},
+		{
+			name:     "should return unhealthy",
+			giveErr:  deploy.CodeUnhealthy.Err(),
+			wantCode: Unhealthy,
+		},
+		{
+			name:     "should return aborted",
+			giveErr:  deploy.CodeAborted.Err(),
+			wantCode: Aborted,
+		},
+		{
+			name:     "should return rolled back",
+			giveErr:  deploy.CodeError.Err(),
+			wantCode: RolledBack,
+		},
+		{
+			name:     "should return already deployed",
+			giveErr:  deploy.ErrVersionAlreadyDeployed,
+			wantCode: AlreadyDeployed,
+		},
+		{
+			name:     "should return hook failed",
+			giveErr:  errors.Wrapf(hook.ErrHookFailed, "%s: %v", hook.PreDeploy, "exit status 1"),
+			wantCode: HookFailed,
+		},
+		{
+			name:     "should return locked",
+			giveErr:  errors.Wrapf(lock.ErrLocked, "local lock of '%s' is held by %s", "app", "alice@host"),
+			wantCode: Locked,
+		},
+		{
+			name:     "should return wait timeout",
+			giveErr:  errors.Wrapf(wait.ErrConditionNotMet, "'%s' of '%s' after %s", "healthy", "2.0.0", "10m0s"),
+			wantCode: WaitTimeout,
+		},
+		{
+			name:     "should return nothing to promote",
+			giveErr:  errors.Wrapf(promote.ErrRolloutInProgress, "load balancer '%s' runs: %s", "app-lb", "1.0.0, 2.0.0"),
+			wantCode: NothingToPromote,
+		},
+		{
+			name:     "should return auth error",
+			giveErr:  errors.Wrap(remitly.ErrForbidden, "could not create instance"),
+			wantCode: Auth,
+		},
+		{
+			name:     "should return config error",
+			giveErr:  profile.ErrProfileNotFound,
+			wantCode: Config,
+		},
+		{
+			name:     "should return not found when instance is not found",
+			giveErr:  instances.ErrInstanceNotFound,
+			wantCode: NotFound,
+		},
+		{
+			name:     "should return not found when load balancer is not found",
+			giveErr:  errors.Wrapf(deploy.ErrLoadBalancerNotFound, "'%s'", "app-lb"),
+			wantCode: NotFound,
+		},
+		{
+			name:     "should return not found when cloud has not found the resource",
+			giveErr:  errors.Wrap(remitly.ErrNotFound, "could not get load balancer"),
+			wantCode: NotFound,
+		},
+
+		{
+			name:     "should return conflict when load balancer to delete is not empty",
+			giveErr:  loadbalancer.ErrLoadBalancerNotEmpty,
+			wantCode: Conflict,
+		},
+		{
+			name:     "should return conflict when load balancer to create already exists",
+			giveErr:  deploy.ErrLoadBalancerAlreadyExists,
+			wantCode: Conflict,
+		},
+		{
+			name:     "should return generic error otherwise",
+			giveErr:  errors.New("unknown flag"),
+			wantCode: Error,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantCode, From(tt.giveErr))
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+)
+
+const (
+	// KindDeployment records a deployment
+	KindDeployment = "deployment"
+	// KindRollback records a rollback of a deployment which has finished, i.e. after another app of its release has failed
+	KindRollback = "rollback"
+	// KindPromotion records a promotion, inside histories of both contexts it has been promoted between
+	KindPromotion = "promotion"
+)
+
+// Record describes a finished deployment
+type Record struct {
+	ID               string    `json:"id" yaml:"id"`
+	Kind             string    `json:"kind" yaml:"kind"`
+	Context          string    `json:"context" yaml:"context"`
+	App              string    `json:"app" yaml:"app"`
+	Revision         string    `json:"revision" yaml:"revision"`
+	PreviousRevision string    `json:"previousRevision,omitempty" yaml:"previousRevision,omitempty"`
+	From             string    `json:"from,omitempty" yaml:"from,omitempty"`
+	To               string    `json:"to,omitempty" yaml:"to,omitempty"`
+	LoadBalancer     string    `json:"loadBalancer" yaml:"loadBalancer"`
+	Replicas         int       `json:"replicas" yaml:"replicas"`
+	Code             string    `json:"code" yaml:"code"`
+	RolledBack       bool      `json:"rolledBack" yaml:"rolledBack"`
+	Error            string    `json:"error,omitempty" yaml:"error,omitempty"`
+	Owner            string    `json:"owner" yaml:"owner"`
+	StartedAt        time.Time `json:"startedAt" yaml:"startedAt"`
+	FinishedAt       time.Time `json:"finishedAt" yaml:"finishedAt"`
+}
+
+// History keeps records of a context as JSON lines inside a file
+type History struct {
+	file string
+}
+
+// New returns History kept inside given file
+func New(file string) History {
+	return History{file: file}
+}
+
+// ForContext returns History of the context kept inside '$REMITLY_PATH/history'
+func ForContext(name string) (History, error) {
+	dir, err := profile.Dir("history")
+	if err != nil {
+		return History{}, err
+	}
+	return New(filepath.Join(dir, name+".jsonl")), nil
+}
+
+// Append adds the record at the end of the history
+func (h History) Append(r Record) error {
+	if err := os.MkdirAll(filepath.Dir(h.file), os.FileMode(0755)); err != nil {
+		return errors.Wrapf(err, "could not create directory: '%s'", filepath.Dir(h.file))
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, os.FileMode(0644))
+	if err != nil {
+		return errors.Wrapf(err, "could not open file: '%s'", h.file)
+	}
+	defer func() { _ = f.Close() }()
+	// a single write of a line keeps lines of concurrent deployments apart
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return errors.Wrapf(err, "could not write into file: '%s'", h.file)
+	}
+	return nil
+}
+
+// Records returns records of the app in order of appending, all records when app is empty.
+// Malformed lines are skipped
+func (h History) Records(app string) ([]Record, error) {
+	f, err := os.Open(h.file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open file: '%s'", h.file)
+	}
+	defer func() { _ = f.Close() }()
+
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			log.WithField("file", h.file).WithError(err).Warn("malformed history record, skipping")
+			continue
+		}
+		if app == "" || r.App == app {
+			records = append(records, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "could not read file: '%s'", h.file)
+	}
+	return records, nil
+}
+
+// Last returns the latest record of the app of one of given kinds, of any kind when none are given.
+// False is returned when there is no such record
+func (h History) Last(app string, kinds ...string) (Record, bool, error) {
+	records, err := h.Records(app)
+	if err != nil {
+		return Record{}, false, err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if len(kinds) == 0 || contains(kinds, records[i].Kind) {
+			return records[i], true, nil
+		}
+	}
+	return Record{}, false, nil
+}
+
+func contains(src []string, s string) bool {
+	for _, item := range src {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	t.Run("should return last record of the app", func(t *testing.T) {
+		// arrange
+		h := New(filepath.Join(t.TempDir(), "history", "default.jsonl"))
+		records := []Record{
+			{ID: "d1", App: "app", Revision: "1.0.0", Code: "success"},
+			{ID: "d2", App: "other_app", Revision: "1.0.0", Code: "success"},
+			{ID: "d3", App: "app", Revision: "2.0.0", Code: "unhealthy", RolledBack: true},
+		}
+
+		// act
+		for _, r := range records {
+			assert.NoError(t, h.Append(r))
+		}
+		last, exists, err := h.Last("app")
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, records[2], last)
+		all, err := h.Records("")
+		assert.NoError(t, err)
+		assert.Len(t, all, 3)
+	})
+
+	t.Run("should return last record of given kinds", func(t *testing.T) {
+		// arrange
+		h := New(filepath.Join(t.TempDir(), "default.jsonl"))
+		records := []Record{
+			{ID: "d1", Kind: KindDeployment, App: "app", Revision: "1.0.0"},
+			{ID: "d2", Kind: KindPromotion, App: "app", Revision: "1.0.0", From: "default", To: "production"},
+		}
+		for _, r := range records {
+			assert.NoError(t, h.Append(r))
+		}
+
+		// act
+		last, exists, err := h.Last("app", KindDeployment, KindRollback)
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, records[0], last)
+	})
+
+	t.Run("should return no record when history does not exist", func(t *testing.T) {
+		// arrange
+		h := New(filepath.Join(t.TempDir(), "default.jsonl"))
+
+		// act
+		_, exists, err := h.Last("app")
+
+		// assert
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("should skip malformed records", func(t *testing.T) {
+		// arrange
+		file := filepath.Join(t.TempDir(), "default.jsonl")
+		content := "{\"id\":\"d1\",\"app\":\"app\"}\n{\"id\":\n{\"id\":\"d2\",\"app\":\"app\"}\n"
+		assert.NoError(t, ioutil.WriteFile(file, []byte(content), os.FileMode(0644)))
+
+		// act
+		records, err := New(file).Records("app")
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, records, 2)
+	})
+}
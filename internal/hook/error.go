@@ -0,0 +1,7 @@
+package hook
+
+import "github.com/pkg/errors"
+
+var (
+	ErrHookFailed = errors.New("hook command failed")
+)
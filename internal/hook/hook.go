@@ -0,0 +1,171 @@
+package hook
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+)
+
+type Kind string
+
+const (
+	// PreDeploy runs before any instance is created, its failure aborts the deployment
+	PreDeploy = Kind("pre-deploy")
+	// PostHealthy runs once instances of the new revision are healthy and the old ones are deleted
+	PostHealthy = Kind("post-healthy")
+	// PreRollback runs before rolling back, its failure aborts the rollback
+	PreRollback = Kind("pre-rollback")
+	// PostDeploy runs once the deployment finished, successfully or not
+	PostDeploy = Kind("post-deploy")
+)
+
+const (
+	timeoutFlag  = "hook-timeout"
+	rollbackFlag = "rollback-on-hook-failure"
+)
+
+var kinds = []Kind{PreDeploy, PostHealthy, PreRollback, PostDeploy}
+
+// Kinds returns all kinds of hooks in order of execution
+func Kinds() []Kind {
+	return append([]Kind{}, kinds...)
+}
+
+// Hooks are shell commands run around a deployment
+type Hooks struct {
+	commands map[Kind]string
+	timeout  time.Duration
+	// Rollback is true when a failure of post-healthy or post-deploy hooks rolls back the deployment
+	Rollback    bool
+	rollbackSet bool
+	// Output receives output of the commands, stdout of the CLI is reserved for its results
+	Output io.Writer
+}
+
+// Env describes the deployment to the commands, keys are names of environment variables
+type Env map[string]string
+
+// AddFlags registers hook flags of given command
+func AddFlags(cmd *cobra.Command) {
+	cmd.Flags().String(string(PreDeploy), "", "Shell command run before deploying, its failure aborts the deployment (optional)")
+	cmd.Flags().String(string(PostHealthy), "", "Shell command run once the new revision is healthy (optional)")
+	cmd.Flags().String(string(PreRollback), "", "Shell command run before rolling back, its failure aborts the rollback (optional)")
+	cmd.Flags().String(string(PostDeploy), "", "Shell command run once the deployment finished, successfully or not (optional)")
+	cmd.Flags().Duration(timeoutFlag, 10*time.Minute, "Time limit of a single hook (optional)")
+	cmd.Flags().Bool(rollbackFlag, false, "Roll back when post-healthy or post-deploy hook of a successful deployment fails (optional)")
+}
+
+// FromFlags returns hooks declared by the flags of given command
+func FromFlags(cmd *cobra.Command) Hooks {
+	h := Hooks{commands: make(map[Kind]string), timeout: 10 * time.Minute, Output: cmd.ErrOrStderr()}
+	for _, kind := range kinds {
+		if command, _ := cmd.Flags().GetString(string(kind)); command != "" {
+			h.commands[kind] = command
+		}
+	}
+	if timeout, err := cmd.Flags().GetDuration(timeoutFlag); err == nil && timeout > 0 {
+		h.timeout = timeout
+	}
+	h.Rollback, _ = cmd.Flags().GetBool(rollbackFlag)
+	h.rollbackSet = cmd.Flags().Changed(rollbackFlag)
+	return h
+}
+
+// WithDefaults returns hooks completed with given commands and rollback setting, flags take precedence
+func (h Hooks) WithDefaults(commands map[Kind]string, rollback bool) Hooks {
+	merged := make(map[Kind]string, len(kinds))
+	for kind, command := range commands {
+		merged[kind] = command
+	}
+	for kind, command := range h.commands {
+		merged[kind] = command
+	}
+	h.commands = merged
+	if !h.rollbackSet {
+		h.Rollback = rollback
+	}
+	return h
+}
+
+// Declared returns true when hook of given kind has a command
+func (h Hooks) Declared(kind Kind) bool {
+	return h.commands[kind] != ""
+}
+
+// Run runs the command of given kind with the environment of the CLI extended by env,
+// does nothing when the hook is not declared
+func (h Hooks) Run(ctx context.Context, kind Kind, env Env) error {
+	command := h.commands[kind]
+	if command == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	hookEnv := Env{"REMITLY_HOOK": string(kind)}
+	for key, value := range env {
+		hookEnv[key] = value
+	}
+
+	f := log.Fields{"hook": kind, "command": command}
+	logging.FromContext(ctx).WithFields(f).Info("running hook")
+	start := time.Now()
+	err := Exec(ctx, command, hookEnv, h.Output)
+	f["milliseconds"] = time.Since(start).Milliseconds()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = errors.Errorf("timed out after %s", h.timeout)
+		}
+		logging.FromContext(ctx).WithFields(f).WithError(err).Error("hook failed")
+		return errors.Wrapf(ErrHookFailed, "%s: %v", kind, err)
+	}
+	logging.FromContext(ctx).WithFields(f).Info("hook succeeded")
+	return nil
+}
+
+// Exec runs the command through 'sh -c' with the environment of the CLI extended by env,
+// its stdout and stderr are written into output, stderr of the CLI when nil
+func Exec(ctx context.Context, command string, env Env, output io.Writer) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = os.Environ()
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		cmd.Env = append(cmd.Env, key+"="+env[key])
+	}
+	if output == nil {
+		output = os.Stderr
+	}
+	cmd.Stdout, cmd.Stderr = output, output
+	return run(ctx, cmd)
+}
+
+// run starts the command and waits for it, the command is killed along with its children once ctx is done
+func run(ctx context.Context, cmd *exec.Cmd) error {
+	isolate(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			kill(cmd)
+		case <-done:
+		}
+	}()
+	return cmd.Wait()
+}
@@ -0,0 +1,141 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCmd(args ...string) *cobra.Command {
+	cmd := &cobra.Command{Use: "deploy"}
+	AddFlags(cmd)
+	_ = cmd.ParseFlags(args)
+	return cmd
+}
+
+func TestHooksRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		giveArgs   []string
+		giveKind   Kind
+		giveEnv    Env
+		wantOutput string
+		wantErr    error
+	}{
+		{
+			name:       "should pass environment describing the deployment",
+			giveArgs:   []string{"--pre-deploy", `echo "$REMITLY_HOOK $REMITLY_APP $REMITLY_REVISION"`},
+			giveKind:   PreDeploy,
+			giveEnv:    Env{"REMITLY_APP": "app", "REMITLY_REVISION": "2.0.0"},
+			wantOutput: "pre-deploy app 2.0.0\n",
+			wantErr:    nil,
+		},
+		{
+			name:       "should do nothing when hook is not declared",
+			giveArgs:   []string{"--pre-deploy", "exit 1"},
+			giveKind:   PostDeploy,
+			giveEnv:    nil,
+			wantOutput: "",
+			wantErr:    nil,
+		},
+		{
+			name:       "should return error when command exits with non-zero code",
+			giveArgs:   []string{"--post-healthy", "echo smoke test failed >&2; exit 3"},
+			giveKind:   PostHealthy,
+			giveEnv:    nil,
+			wantOutput: "smoke test failed\n",
+			wantErr:    ErrHookFailed,
+		},
+		{
+			name:       "should return error when command exceeds timeout",
+			giveArgs:   []string{"--pre-rollback", "sleep 5", "--hook-timeout", "50ms"},
+			giveKind:   PreRollback,
+			giveEnv:    nil,
+			wantOutput: "",
+			wantErr:    ErrHookFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			var output bytes.Buffer
+			h := FromFlags(newCmd(tt.giveArgs...))
+			h.Output = &output
+
+			// act
+			start := time.Now()
+			err := h.Run(context.Background(), tt.giveKind, tt.giveEnv)
+
+			// assert
+			assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+			assert.Equal(t, tt.wantOutput, output.String())
+			assert.True(t, time.Since(start) < 5*time.Second)
+		})
+	}
+}
+
+func TestHooksWithDefaults(t *testing.T) {
+	tests := []struct {
+		name         string
+		giveArgs     []string
+		giveCommands map[Kind]string
+		giveRollback bool
+		wantDeclared []Kind
+		wantCommand  string
+		wantRollback bool
+	}{
+		{
+			name:         "should use commands of the context",
+			giveArgs:     nil,
+			giveCommands: map[Kind]string{PreDeploy: "echo context", PostDeploy: "true"},
+			giveRollback: true,
+			wantDeclared: []Kind{PreDeploy, PostDeploy},
+			wantCommand:  "context\n",
+			wantRollback: true,
+		},
+		{
+			name:         "should prefer flags over the context",
+			giveArgs:     []string{"--pre-deploy", "echo flag", "--rollback-on-hook-failure=false"},
+			giveCommands: map[Kind]string{PreDeploy: "echo context"},
+			giveRollback: true,
+			wantDeclared: []Kind{PreDeploy},
+			wantCommand:  "flag\n",
+			wantRollback: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			var output bytes.Buffer
+			h := FromFlags(newCmd(tt.giveArgs...)).WithDefaults(tt.giveCommands, tt.giveRollback)
+			h.Output = &output
+
+			// act
+			err := h.Run(context.Background(), PreDeploy, nil)
+
+			// assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCommand, output.String())
+			assert.Equal(t, tt.wantRollback, h.Rollback)
+			for _, kind := range Kinds() {
+				assert.Equal(t, contains(tt.wantDeclared, kind), h.Declared(kind), string(kind))
+			}
+		})
+	}
+}
+
+func contains(kinds []Kind, kind Kind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package hook
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// isolate starts the command in a process group of its own
+func isolate(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// kill kills the process group of the command, so children holding its output do not outlive it
+func kill(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,201 @@
+package instances
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/output"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+const (
+	version = "1.0.0"
+)
+
+type cmdContext struct {
+	app          string
+	revision     string
+	status       string
+	allUnhealthy bool
+	printer      output.Printer
+}
+
+func NewCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "instances",
+		Version: version,
+		Short:   "A subcommand for inspecting instances of the application",
+		Long: `
+A subcommand for inspecting and removing instances
+of the application running in the remote cloud.
+
+Subcommand uses:
+	'REMITLY_PROFILE' - environment variable (optional, default: default)
+	'REMITLY_PATH' - created by 'remitly initialize ...' (required)
+`,
+	}
+
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newGetCmd())
+	cmd.AddCommand(newDeleteCmd())
+
+	return &cmd
+}
+
+func newListCmd() *cobra.Command {
+	var c cmdContext
+	cmd := cobra.Command{
+		Use:     "list",
+		Short:   "Lists instances of the application",
+		Args:    cobra.NoArgs,
+		PreRunE: c.preRun,
+		RunE:    c.list,
+	}
+	c.addAppFlag(&cmd)
+	cmd.Flags().StringVar(&c.revision, "revision", "", "Show only instances of given version of the application (optional)")
+	cmd.Flags().StringVar(&c.status, "status", "", "Show only instances with given status, i.e. healthy (optional)")
+	return &cmd
+}
+
+func newGetCmd() *cobra.Command {
+	var c cmdContext
+	cmd := cobra.Command{
+		Use:     "get ID",
+		Short:   "Shows instance of the application",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: c.preRun,
+		RunE:    c.get,
+	}
+	c.addAppFlag(&cmd)
+	return &cmd
+}
+
+func newDeleteCmd() *cobra.Command {
+	var c cmdContext
+	cmd := cobra.Command{
+		Use:   "delete [ID]",
+		Short: "Deletes instance of the application",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if c.allUnhealthy {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		PreRunE: c.preRun,
+		RunE:    c.delete,
+	}
+	c.addAppFlag(&cmd)
+	cmd.Flags().BoolVar(&c.allUnhealthy, "all-unhealthy", false, "Delete all unhealthy instances instead of the one with given ID (optional)")
+	return &cmd
+}
+
+func (c *cmdContext) addAppFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&c.app, "application", "a", "", "Application name (required)")
+	cmd.MarkFlagRequired("application")
+}
+
+func (c *cmdContext) preRun(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if err := profile.LoadSettings(cmd, args); err != nil {
+		return err
+	}
+	p, err := output.NewPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	c.printer = p
+	return nil
+}
+
+func (c *cmdContext) client() (remitly.Clienter, string, error) {
+	pc, err := profile.Current()
+	if err != nil {
+		return nil, "", err
+	}
+	rc, err := pc.NewClient()
+	if err != nil {
+		return nil, "", err
+	}
+	lb, err := pc.LoadBalancer(c.app)
+	if err != nil {
+		return nil, "", err
+	}
+	return rc, lb, nil
+}
+
+func (c *cmdContext) list(cmd *cobra.Command, _ []string) error {
+	rc, lb, err := c.client()
+	if err != nil {
+		return err
+	}
+	instances, err := rc.GetInstances(cmd.Context(), lb)
+	if err != nil {
+		return err
+	}
+	return c.printer.Print(filter(instances, c.revision, c.status))
+}
+
+func (c *cmdContext) get(cmd *cobra.Command, args []string) error {
+	rc, lb, err := c.client()
+	if err != nil {
+		return err
+	}
+	instances, err := rc.GetInstances(cmd.Context(), lb)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if instance.ID == args[0] {
+			return c.printer.Print(instanceDetail(instance))
+		}
+	}
+	return ErrInstanceNotFound
+}
+
+func (c *cmdContext) delete(cmd *cobra.Command, args []string) error {
+	rc, lb, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	IDs := args
+	if c.allUnhealthy {
+		instances, err := rc.GetInstances(cmd.Context(), lb)
+		if err != nil {
+			return err
+		}
+		IDs = make([]string, 0)
+		for _, instance := range filter(instances, "", fmt.Sprint(remitly.StateUnhealthy)) {
+			IDs = append(IDs, instance.ID)
+		}
+	}
+
+	deleted := make([]string, 0, len(IDs))
+	for _, ID := range IDs {
+		if err := rc.DeleteInstance(cmd.Context(), lb, ID); err != nil {
+			f := log.Fields{"name": lb, "id": ID}
+			log.WithContext(cmd.Context()).WithFields(f).WithError(err).Error("could not delete instance")
+			return err
+		}
+		deleted = append(deleted, ID)
+	}
+	return c.printer.Print(deletedList(deleted))
+}
+
+// filter returns instances matching given version and status, empty values match everything
+func filter(src []remitly.Instance, version, status string) instanceList {
+	result := make(instanceList, 0, len(src))
+	for _, instance := range src {
+		if version != "" && instance.Version != version {
+			continue
+		}
+		if status != "" && fmt.Sprint(instance.Status) != status {
+			continue
+		}
+		result = append(result, instance)
+	}
+	return result
+}
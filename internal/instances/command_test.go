@@ -0,0 +1,74 @@
+package instances
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+func TestNewCmd(t *testing.T) {
+	t.Run("should return command with list, get and delete subcommands", func(t *testing.T) {
+		// arrange
+
+		// act
+		cmd := NewCmd()
+
+		// assert
+		for _, name := range []string{"list", "get", "delete"} {
+			sub, _, err := cmd.Find([]string{name})
+			assert.NoError(t, err)
+			assert.Equal(t, name, sub.Name())
+			assert.NotNil(t, sub.Flag("application"))
+		}
+	})
+}
+
+func TestFilter(t *testing.T) {
+	instances := []remitly.Instance{
+		{ID: "ins_1", Status: remitly.StateHealthy, Version: "1"},
+		{ID: "ins_2", Status: remitly.StateUnhealthy, Version: "1"},
+		{ID: "ins_3", Status: remitly.StateHealthy, Version: "2"},
+	}
+
+	tests := []struct {
+		name        string
+		giveVersion string
+		giveStatus  string
+		wantIDs     []string
+	}{
+		{
+			name:    "should return all instances when no filters",
+			wantIDs: []string{"ins_1", "ins_2", "ins_3"},
+		},
+		{
+			name:        "should filter by version",
+			giveVersion: "1",
+			wantIDs:     []string{"ins_1", "ins_2"},
+		},
+		{
+			name:        "should filter by version and status",
+			giveVersion: "1",
+			giveStatus:  "unhealthy",
+			wantIDs:     []string{"ins_2"},
+		},
+		{
+			name:       "should return empty list when nothing matches",
+			giveStatus: "provisioning",
+			wantIDs:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := filter(instances, tt.giveVersion, tt.giveStatus)
+
+			IDs := make([]string, 0)
+			for _, instance := range result {
+				IDs = append(IDs, instance.ID)
+			}
+			assert.Equal(t, tt.wantIDs, IDs)
+		})
+	}
+}
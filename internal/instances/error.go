@@ -0,0 +1,7 @@
+package instances
+
+import "github.com/pkg/errors"
+
+var (
+	ErrInstanceNotFound = errors.New("instance with given ID was not found within the application's load balancer")
+)
@@ -0,0 +1,33 @@
+package instances
+
+import (
+	"fmt"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+type instanceList []remitly.Instance
+
+func (l instanceList) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(l))
+	for _, instance := range l {
+		rows = append(rows, []string{instance.ID, instance.Version, fmt.Sprint(instance.Status)})
+	}
+	return []string{"ID", "VERSION", "STATUS"}, rows
+}
+
+type deletedList []string
+
+func (l deletedList) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(l))
+	for _, ID := range l {
+		rows = append(rows, []string{ID})
+	}
+	return []string{"DELETED"}, rows
+}
+
+type instanceDetail remitly.Instance
+
+func (d instanceDetail) Table() ([]string, [][]string) {
+	return instanceList{remitly.Instance(d)}.Table()
+}
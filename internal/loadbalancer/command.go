@@ -0,0 +1,187 @@
+package loadbalancer
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/output"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+const (
+	version = "1.0.0"
+)
+
+type cmdContext struct {
+	app     string
+	name    string
+	force   bool
+	printer output.Printer
+}
+
+func NewCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "lb",
+		Version: version,
+		Short:   "A subcommand for managing load balancers",
+		Long: `
+A subcommand for listing, creating and deleting
+load balancers in the remote cloud.
+
+Subcommand uses:
+	'REMITLY_PROFILE' - environment variable (optional, default: default)
+	'REMITLY_PATH' - created by 'remitly initialize ...' (required)
+`,
+	}
+
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newCreateCmd())
+	cmd.AddCommand(newDeleteCmd())
+
+	return &cmd
+}
+
+func newListCmd() *cobra.Command {
+	var c cmdContext
+	cmd := cobra.Command{
+		Use:     "list",
+		Short:   "Lists load balancers",
+		Args:    cobra.NoArgs,
+		PreRunE: c.preRun,
+		RunE:    c.list,
+	}
+	return &cmd
+}
+
+func newCreateCmd() *cobra.Command {
+	var c cmdContext
+	cmd := cobra.Command{
+		Use:     "create",
+		Short:   "Creates load balancer of the application",
+		Args:    cobra.NoArgs,
+		PreRunE: c.preRun,
+		RunE:    c.create,
+	}
+	c.addNameFlags(&cmd)
+	return &cmd
+}
+
+func newDeleteCmd() *cobra.Command {
+	var c cmdContext
+	cmd := cobra.Command{
+		Use:     "delete",
+		Short:   "Deletes load balancer of the application",
+		Args:    cobra.NoArgs,
+		PreRunE: c.preRun,
+		RunE:    c.delete,
+	}
+	c.addNameFlags(&cmd)
+	cmd.Flags().BoolVar(&c.force, "force", false, "Delete all instances of the load balancer before deleting it (optional)")
+	return &cmd
+}
+
+func (c *cmdContext) addNameFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&c.app, "application", "a", "", "Application name, the load balancer name is derived from it")
+	cmd.Flags().StringVar(&c.name, "load-balancer", "", "Load balancer name, used instead of the one derived from --application")
+}
+
+func (c *cmdContext) preRun(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if cmd.Flag("load-balancer") != nil && c.app == "" && c.name == "" {
+		return ErrNameNotSpecified
+	}
+	if err := profile.LoadSettings(cmd, args); err != nil {
+		return err
+	}
+	p, err := output.NewPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	c.printer = p
+	return nil
+}
+
+func (c *cmdContext) client() (remitly.Clienter, string, error) {
+	pc, err := profile.Current()
+	if err != nil {
+		return nil, "", err
+	}
+	rc, err := pc.NewClient()
+	if err != nil {
+		return nil, "", err
+	}
+	name := c.name
+	if name == "" {
+		if name, err = pc.LoadBalancer(c.app); err != nil {
+			return nil, "", err
+		}
+	}
+	return rc, name, nil
+}
+
+func (c *cmdContext) list(cmd *cobra.Command, _ []string) error {
+	rc, _, err := c.client()
+	if err != nil {
+		return err
+	}
+	lbs, err := rc.ListLoadBalancers(cmd.Context())
+	if err != nil {
+		return err
+	}
+	return c.printer.Print(loadBalancerList(lbs))
+}
+
+func (c *cmdContext) create(cmd *cobra.Command, _ []string) error {
+	rc, name, err := c.client()
+	if err != nil {
+		return err
+	}
+	lb, err := rc.CreateLoadBalancer(cmd.Context(), name)
+	if err != nil {
+		log.WithContext(cmd.Context()).WithField("name", name).WithError(err).Error("could not create load balancer")
+		return err
+	}
+	log.WithContext(cmd.Context()).WithField("name", name).Info("load balancer successfully created")
+	return c.printer.Print(loadBalancerList{lb})
+}
+
+func (c *cmdContext) delete(cmd *cobra.Command, _ []string) error {
+	rc, name, err := c.client()
+	if err != nil {
+		return err
+	}
+	removed, err := remove(cmd.Context(), rc, name, c.force)
+	if err != nil {
+		return err
+	}
+	log.WithContext(cmd.Context()).WithField("name", name).Info("load balancer successfully deleted")
+	return c.printer.Print(deleted{Name: name, Instances: removed})
+}
+
+// remove deletes load balancer with given name, instances of the load balancer
+// are deleted beforehand only when forced, returns the number of deleted instances
+func remove(ctx context.Context, rc remitly.Clienter, name string, force bool) (int, error) {
+	instances, err := rc.GetInstances(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if len(instances) > 0 && !force {
+		return 0, ErrLoadBalancerNotEmpty
+	}
+	for i, instance := range instances {
+		if err := rc.DeleteInstance(ctx, name, instance.ID); err != nil {
+			f := log.Fields{"name": name, "id": instance.ID}
+			log.WithContext(ctx).WithFields(f).WithError(err).Error("could not delete instance")
+			return i, err
+		}
+	}
+
+	if err := rc.DeleteLoadBalancer(ctx, name); err != nil {
+		log.WithContext(ctx).WithField("name", name).WithError(err).Error("could not delete load balancer")
+		return len(instances), err
+	}
+	return len(instances), nil
+}
@@ -0,0 +1,95 @@
+package loadbalancer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+	mockRemitly "github.com/mazxaxz/remitly-cli/pkg/remitly/mocks"
+)
+
+func TestRemove(t *testing.T) {
+	t.Run("should delete empty load balancer", func(t *testing.T) {
+		// arrange
+		const loadBalancerName = "lb_1"
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+		// expected calls
+		mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), loadBalancerName).Return([]remitly.Instance{}, nil)
+		mockRemitlyClient.EXPECT().DeleteLoadBalancer(gomock.Any(), loadBalancerName).Return(nil)
+
+		// act
+		removed, err := remove(context.Background(), mockRemitlyClient, loadBalancerName, false)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 0, removed)
+	})
+
+	t.Run("should refuse to delete load balancer with instances when not forced", func(t *testing.T) {
+		// arrange
+		const loadBalancerName = "lb_1"
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+		instances := []remitly.Instance{
+			{
+				ID:      "ins_1",
+				Status:  remitly.StateHealthy,
+				Version: "1",
+			},
+		}
+
+		// expected calls
+		mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), loadBalancerName).Return(instances, nil)
+
+		// act
+		removed, err := remove(context.Background(), mockRemitlyClient, loadBalancerName, false)
+
+		// assert
+		assert.Equal(t, ErrLoadBalancerNotEmpty, err)
+		assert.Equal(t, 0, removed)
+	})
+
+	t.Run("should delete instances before load balancer when forced", func(t *testing.T) {
+		// arrange
+		const loadBalancerName = "lb_1"
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+		instances := []remitly.Instance{
+			{
+				ID:      "ins_1",
+				Status:  remitly.StateHealthy,
+				Version: "1",
+			},
+			{
+				ID:      "ins_2",
+				Status:  remitly.StateUnhealthy,
+				Version: "1",
+			},
+		}
+
+		// expected calls
+		gomock.InOrder(
+			mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), loadBalancerName).Return(instances, nil),
+			mockRemitlyClient.EXPECT().DeleteInstance(gomock.Any(), loadBalancerName, instances[0].ID).Return(nil),
+			mockRemitlyClient.EXPECT().DeleteInstance(gomock.Any(), loadBalancerName, instances[1].ID).Return(nil),
+			mockRemitlyClient.EXPECT().DeleteLoadBalancer(gomock.Any(), loadBalancerName).Return(nil),
+		)
+
+		// act
+		removed, err := remove(context.Background(), mockRemitlyClient, loadBalancerName, true)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, removed)
+	})
+}
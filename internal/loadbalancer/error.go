@@ -0,0 +1,8 @@
+package loadbalancer
+
+import "github.com/pkg/errors"
+
+var (
+	ErrNameNotSpecified     = errors.New("either --application or --load-balancer flag has to be specified")
+	ErrLoadBalancerNotEmpty = errors.New("load balancer still has instances, use --force flag to delete them as well")
+)
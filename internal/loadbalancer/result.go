@@ -0,0 +1,26 @@
+package loadbalancer
+
+import (
+	"strconv"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+type loadBalancerList []remitly.LoadBalancer
+
+func (l loadBalancerList) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(l))
+	for _, lb := range l {
+		rows = append(rows, []string{lb.Name})
+	}
+	return []string{"NAME"}, rows
+}
+
+type deleted struct {
+	Name      string `json:"name" yaml:"name"`
+	Instances int    `json:"deletedInstances" yaml:"deletedInstances"`
+}
+
+func (d deleted) Table() ([]string, [][]string) {
+	return []string{"DELETED", "INSTANCES"}, [][]string{{d.Name, strconv.Itoa(d.Instances)}}
+}
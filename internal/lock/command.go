@@ -0,0 +1,151 @@
+package lock
+
+import (
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/output"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+const (
+	version = "1.0.0"
+)
+
+type cmdContext struct {
+	app     string
+	remote  bool
+	force   bool
+	printer output.Printer
+}
+
+func NewCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "lock",
+		Version: version,
+		Short:   "A subcommand for inspecting and releasing deployment locks",
+		Long: `
+A subcommand for inspecting and releasing locks, which
+prevent concurrent deployments of the same application.
+
+Subcommand uses:
+	'REMITLY_PROFILE' - environment variable (optional, default: default)
+	'REMITLY_PATH' - created by 'remitly initialize ...' (required)
+	'REMITLY_LOCK_OWNER' - environment variable (optional, default: user@host)
+`,
+	}
+
+	cmd.AddCommand(newStatusCmd())
+	cmd.AddCommand(newReleaseCmd())
+
+	return &cmd
+}
+
+func newStatusCmd() *cobra.Command {
+	var c cmdContext
+	cmd := cobra.Command{
+		Use:     "status",
+		Short:   "Shows locks held on the application",
+		Args:    cobra.NoArgs,
+		PreRunE: c.preRun,
+		RunE:    c.status,
+	}
+	c.addFlags(&cmd)
+	return &cmd
+}
+
+func newReleaseCmd() *cobra.Command {
+	var c cmdContext
+	cmd := cobra.Command{
+		Use:     "release",
+		Short:   "Releases locks held on the application",
+		Args:    cobra.NoArgs,
+		PreRunE: c.preRun,
+		RunE:    c.release,
+	}
+	c.addFlags(&cmd)
+	cmd.Flags().BoolVar(&c.force, "force", false, "Release locks held by other owners, i.e. of a crashed deployment (optional)")
+	return &cmd
+}
+
+func (c *cmdContext) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&c.app, "application", "a", "", "Application name (required)")
+	cmd.MarkFlagRequired("application")
+	cmd.Flags().BoolVar(&c.remote, "remote", false, "Include the lock held through the cloud (optional, default: contexts[].lock.remote)")
+}
+
+func (c *cmdContext) preRun(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if err := profile.LoadSettings(cmd, args); err != nil {
+		return err
+	}
+	p, err := output.NewPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	c.printer = p
+	return nil
+}
+
+func (c *cmdContext) manager() (*Manager, error) {
+	pc, err := profile.Current()
+	if err != nil {
+		return nil, err
+	}
+	var remote remitly.Locker
+	if c.remote || pc.RemoteLock() {
+		rc, err := pc.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		remote = Remote(rc)
+	}
+	return ForContext(pc, remote)
+}
+
+func (c *cmdContext) status(cmd *cobra.Command, _ []string) error {
+	m, err := c.manager()
+	if err != nil {
+		return err
+	}
+	statuses, err := m.Status(cmd.Context(), c.app)
+	if err != nil {
+		return err
+	}
+	return c.printer.Print(statuses)
+}
+
+func (c *cmdContext) release(cmd *cobra.Command, _ []string) error {
+	m, err := c.manager()
+	if err != nil {
+		return err
+	}
+	released, err := m.Release(cmd.Context(), c.app, c.force)
+	if err != nil {
+		return err
+	}
+	log.WithContext(cmd.Context()).WithFields(log.Fields{"app": c.app, "released": len(released)}).Info("locks released")
+	return c.printer.Print(released)
+}
+
+// ForContext returns Manager keeping lock files of the context inside '$REMITLY_PATH/locks'
+func ForContext(pc profile.Context, remote remitly.Locker) (*Manager, error) {
+	dir, err := profile.Dir("locks")
+	if err != nil {
+		return nil, err
+	}
+	return New(filepath.Join(dir, pc.Name()), remote), nil
+}
+
+// Remote returns the lock endpoints of the client, nil when the client does not serve them
+func Remote(rc remitly.Clienter) remitly.Locker {
+	locker, ok := rc.(remitly.Locker)
+	if !ok {
+		log.Warn("client does not support remote locks, only local lock is used")
+		return nil
+	}
+	return locker
+}
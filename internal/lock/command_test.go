@@ -0,0 +1,27 @@
+package lock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCmd(t *testing.T) {
+	t.Run("should return command with status and release subcommands", func(t *testing.T) {
+		// arrange
+
+		// act
+		cmd := NewCmd()
+
+		// assert
+		for _, name := range []string{"status", "release"} {
+			sub, _, err := cmd.Find([]string{name})
+			assert.NoError(t, err)
+			assert.Equal(t, name, sub.Name())
+			assert.NotNil(t, sub.Flag("application"))
+			assert.NotNil(t, sub.Flag("remote"))
+		}
+		release, _, _ := cmd.Find([]string{"release"})
+		assert.NotNil(t, release.Flag("force"))
+	})
+}
@@ -0,0 +1,8 @@
+package lock
+
+import "github.com/pkg/errors"
+
+var (
+	ErrLocked   = errors.New("app is locked by another deployment")
+	ErrNotOwner = errors.New("lock is held by another owner, use --force to release it")
+)
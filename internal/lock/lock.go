@@ -0,0 +1,346 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+const (
+	// OwnerEnv overrides the owner of locks, 'user@host' by default
+	OwnerEnv = "REMITLY_LOCK_OWNER"
+	// DefaultTTL is the time after which a lock of a deployment which has not released it is considered stale
+	DefaultTTL = 30 * time.Minute
+
+	ScopeLocal  = "local"
+	ScopeRemote = "remote"
+)
+
+// Options of locks taken by a deployment
+type Options struct {
+	TTL    time.Duration
+	Remote bool
+}
+
+// AddFlags registers lock flags of given command
+func AddFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("lock-ttl", DefaultTTL, "Time after which the lock of the app is considered stale (optional)")
+	cmd.Flags().Bool("remote-lock", false, "Lock the app through the lock endpoint of the cloud as well (optional, default: contexts[].lock.remote)")
+}
+
+// FromFlags returns lock options set by the flags of given command
+func FromFlags(cmd *cobra.Command) Options {
+	o := Options{TTL: DefaultTTL}
+	if ttl, err := cmd.Flags().GetDuration("lock-ttl"); err == nil && ttl > 0 {
+		o.TTL = ttl
+	}
+	o.Remote, _ = cmd.Flags().GetBool("remote-lock")
+	return o
+}
+
+// Owner returns the owner of locks taken by this process
+func Owner() string {
+	if owner := os.Getenv(OwnerEnv); owner != "" {
+		return owner
+	}
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	return name + "@" + hostname()
+}
+
+// record is the content of a local lock file
+type record struct {
+	Owner        string    `json:"owner"`
+	DeploymentID string    `json:"deploymentId,omitempty"`
+	Host         string    `json:"host"`
+	PID          int       `json:"pid"`
+	AcquiredAt   time.Time `json:"acquiredAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// stale returns true when the lock expired or the process holding it on this machine is gone
+func (r record) stale(now time.Time) bool {
+	return !r.ExpiresAt.After(now) || (r.Host == hostname() && !alive(r.PID))
+}
+
+// same returns true when both records describe the same lock
+func (r record) same(o record) bool {
+	return r.Owner == o.Owner && r.DeploymentID == o.DeploymentID && r.Host == o.Host && r.PID == o.PID &&
+		r.AcquiredAt.Equal(o.AcquiredAt) && r.ExpiresAt.Equal(o.ExpiresAt)
+}
+
+// Manager locks apps with files inside a directory, and through the cloud when remote is not nil
+type Manager struct {
+	dir    string
+	remote remitly.Locker
+	owner  string
+	now    func() time.Time
+}
+
+// New returns Manager keeping lock files inside dir
+func New(dir string, remote remitly.Locker) *Manager {
+	return &Manager{dir: dir, remote: remote, owner: Owner(), now: time.Now}
+}
+
+// Acquire locks the app for the deployment, the returned function releases the lock.
+// The error wraps ErrLocked and describes the holder when the app is locked by another deployment
+func (m *Manager) Acquire(ctx context.Context, app, deploymentID string, ttl time.Duration) (func(), error) {
+	if err := m.acquireLocal(app, deploymentID, ttl); err != nil {
+		return nil, err
+	}
+	f := log.Fields{"app": app, "owner": m.owner}
+	release := func() {
+		if err := m.releaseLocal(app, deploymentID, false); err != nil {
+			logging.FromContext(ctx).WithFields(f).WithError(err).Warn("could not release local lock")
+		}
+	}
+	if m.remote == nil {
+		logging.FromContext(ctx).WithFields(f).Debug("app locked")
+		return release, nil
+	}
+
+	p := remitly.AcquireLockParams{Owner: m.owner, DeploymentID: deploymentID, TTLSeconds: int64(ttl.Seconds())}
+	current, err := m.remote.AcquireLock(ctx, app, p)
+	if err != nil {
+		release()
+		if errors.Is(err, remitly.ErrLocked) {
+			return nil, locked(ScopeRemote, app, current.Owner, current.DeploymentID, current.AcquiredAt, current.ExpiresAt)
+		}
+		return nil, errors.Wrap(err, "could not acquire remote lock")
+	}
+	logging.FromContext(ctx).WithFields(f).Debug("app locked")
+	return func() {
+		// the deployment may have been cancelled, the lock is released regardless
+		p := remitly.ReleaseLockParams{Owner: m.owner, DeploymentID: deploymentID}
+		if err := m.remote.ReleaseLock(context.Background(), app, p); err != nil && !errors.Is(err, remitly.ErrNotFound) {
+			logging.FromContext(ctx).WithFields(f).WithError(err).Warn("could not release remote lock")
+		}
+		release()
+	}, nil
+}
+
+// Status returns locks held on the app, including expired and stale local ones
+func (m *Manager) Status(ctx context.Context, app string) (Statuses, error) {
+	statuses := make(Statuses, 0, 2)
+	r, err := m.read(app)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		statuses = append(statuses, m.localStatus(app, r))
+	}
+	if m.remote == nil {
+		return statuses, nil
+	}
+	l, err := m.remote.GetLock(ctx, app)
+	if err != nil {
+		if errors.Is(err, remitly.ErrNotFound) {
+			return statuses, nil
+		}
+		return nil, errors.Wrap(err, "could not get remote lock")
+	}
+	return append(statuses, m.remoteStatus(app, l)), nil
+}
+
+// Release releases locks held on the app by the owner of this process, regardless of their owner when forced,
+// and returns the released locks
+func (m *Manager) Release(ctx context.Context, app string, force bool) (Statuses, error) {
+	held, err := m.Status(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	released := make(Statuses, 0, len(held))
+	for _, s := range held {
+		if s.Owner != m.owner && !force {
+			return released, errors.Wrapf(ErrNotOwner, "%s lock of '%s' is held by '%s'", s.Scope, app, s.Owner)
+		}
+		switch s.Scope {
+		case ScopeLocal:
+			err = m.releaseLocal(app, "", force)
+		case ScopeRemote:
+			err = m.remote.ReleaseLock(ctx, app, remitly.ReleaseLockParams{Owner: m.owner, Force: force})
+		}
+		if err != nil && !errors.Is(err, remitly.ErrNotFound) && !os.IsNotExist(err) {
+			return released, err
+		}
+		released = append(released, s)
+	}
+	return released, nil
+}
+
+func (m *Manager) acquireLocal(app, deploymentID string, ttl time.Duration) error {
+	if err := os.MkdirAll(m.dir, os.FileMode(0755)); err != nil {
+		return errors.Wrapf(err, "could not create directory: '%s'", m.dir)
+	}
+	now := m.now()
+	r := record{
+		Owner:        m.owner,
+		DeploymentID: deploymentID,
+		Host:         hostname(),
+		PID:          os.Getpid(),
+		AcquiredAt:   now,
+		ExpiresAt:    now.Add(ttl),
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	// the lock file is linked from a complete temporary file, so it is created atomically with its content
+	tmp, err := ioutil.TempFile(m.dir, ".lock-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	file := m.file(app)
+	for attempt := 0; attempt < 2; attempt++ {
+		err := os.Link(tmp.Name(), file)
+		if err == nil {
+			return nil
+		}
+		if !os.IsExist(err) {
+			return errors.Wrapf(err, "could not create lock file: '%s'", file)
+		}
+		current, err := m.read(app)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !current.stale(now) {
+			return locked(ScopeLocal, app, current.Owner, current.DeploymentID, current.AcquiredAt, current.ExpiresAt)
+		}
+		done, err := m.takeOver(app, current)
+		if err != nil {
+			return err
+		}
+		defer done()
+	}
+	return errors.Wrapf(ErrLocked, "could not acquire local lock of '%s'", app)
+}
+
+// takeOver removes the stale lock, the returned function has to be called once the lock of the deployment
+// has been created. Deployments which have found the same stale lock race for a takeover file, only the winner
+// removes the lock, so it never removes a lock which has replaced the stale one in the meantime
+func (m *Manager) takeOver(app string, stale record) (func(), error) {
+	file := m.file(app)
+	takeover := fmt.Sprintf("%s.takeover-%d-%d", file, stale.AcquiredAt.UnixNano(), stale.PID)
+	f, err := os.OpenFile(takeover, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.FileMode(0644))
+	if os.IsExist(err) {
+		return nil, errors.Wrapf(ErrLocked, "stale local lock of '%s' is being taken over by another deployment", app)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create takeover file: '%s'", takeover)
+	}
+	_ = f.Close()
+	done := func() { _ = os.Remove(takeover) }
+
+	current, err := m.read(app)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		done()
+		return nil, err
+	}
+	if !current.same(stale) {
+		done()
+		return nil, locked(ScopeLocal, app, current.Owner, current.DeploymentID, current.AcquiredAt, current.ExpiresAt)
+	}
+	log.WithFields(log.Fields{"app": app, "owner": current.Owner}).Warn("removing stale lock")
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		done()
+		return nil, err
+	}
+	return done, nil
+}
+
+// releaseLocal removes the lock file when it belongs to the deployment, or when forced
+func (m *Manager) releaseLocal(app, deploymentID string, force bool) error {
+	r, err := m.read(app)
+	if err != nil {
+		return err
+	}
+	if !force && (r.Owner != m.owner || (deploymentID != "" && r.DeploymentID != deploymentID)) {
+		return nil
+	}
+	if force {
+		// takeover files of deployments which have crashed while taking the lock over are left behind
+		takeovers, _ := filepath.Glob(m.file(app) + ".takeover-*")
+		for _, f := range takeovers {
+			_ = os.Remove(f)
+		}
+	}
+	return os.Remove(m.file(app))
+}
+
+func (m *Manager) read(app string) (record, error) {
+	b, err := ioutil.ReadFile(m.file(app))
+	if err != nil {
+		return record{}, err
+	}
+	var r record
+	if err := json.Unmarshal(b, &r); err != nil {
+		return record{}, errors.Wrapf(err, "could not parse lock file: '%s'", m.file(app))
+	}
+	return r, nil
+}
+
+func (m *Manager) file(app string) string {
+	return filepath.Join(m.dir, app+".lock")
+}
+
+func (m *Manager) localStatus(app string, r record) Status {
+	state := StateHeld
+	if r.stale(m.now()) {
+		state = StateStale
+	}
+	return Status{Scope: ScopeLocal, App: app, Owner: r.Owner, DeploymentID: r.DeploymentID, AcquiredAt: r.AcquiredAt, ExpiresAt: r.ExpiresAt, State: state}
+}
+
+func (m *Manager) remoteStatus(app string, l remitly.Lock) Status {
+	state := StateHeld
+	if l.Expired(m.now()) {
+		state = StateStale
+	}
+	return Status{Scope: ScopeRemote, App: app, Owner: l.Owner, DeploymentID: l.DeploymentID, AcquiredAt: l.AcquiredAt, ExpiresAt: l.ExpiresAt, State: state}
+}
+
+func locked(scope, app, owner, deploymentID string, acquired, expires time.Time) error {
+	holder := owner
+	if deploymentID != "" {
+		holder = fmt.Sprintf("%s (deployment %s)", owner, deploymentID)
+	}
+	return errors.Wrapf(ErrLocked, "%s lock of '%s' is held by %s since %s until %s, release it with 'remitly lock release -a %s --force' once stale",
+		scope, app, holder, acquired.Format(time.RFC3339), expires.Format(time.RFC3339), app)
+}
+
+func hostname() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+	return host
+}
@@ -0,0 +1,252 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly/remitlytest"
+)
+
+func newManager(dir, owner string, remote remitly.Locker) *Manager {
+	m := New(dir, remote)
+	m.owner = owner
+	return m
+}
+
+func TestManagerAcquire(t *testing.T) {
+	t.Run("should block deployment of the app locked by another owner until released", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		alice, bob := newManager(dir, "alice@host", nil), newManager(dir, "bob@host", nil)
+		ctx := context.Background()
+
+		// act
+		release, err := alice.Acquire(ctx, "app", "d1", time.Minute)
+		_, blocked := bob.Acquire(ctx, "app", "d2", time.Minute)
+		_, other := bob.Acquire(ctx, "other_app", "d3", time.Minute)
+		release()
+		_, acquired := bob.Acquire(ctx, "app", "d2", time.Minute)
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, errors.Is(blocked, ErrLocked), "unexpected error: %v", blocked)
+		assert.Contains(t, blocked.Error(), "alice@host (deployment d1)")
+		assert.NoError(t, other)
+		assert.NoError(t, acquired)
+	})
+
+	t.Run("should replace stale locks", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			giveRecord func(r *record)
+		}{
+			{
+				name:       "should replace expired lock",
+				giveRecord: func(r *record) { r.ExpiresAt = time.Now().Add(-time.Second) },
+			},
+			{
+				name: "should replace lock of a finished process on this machine",
+				giveRecord: func(r *record) {
+					cmd := exec.Command("true")
+					_ = cmd.Run()
+					r.PID = cmd.ProcessState.Pid()
+				},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// arrange
+				dir := t.TempDir()
+				alice, bob := newManager(dir, "alice@host", nil), newManager(dir, "bob@host", nil)
+				_, err := alice.Acquire(context.Background(), "app", "d1", time.Minute)
+				assert.NoError(t, err)
+				r, _ := alice.read("app")
+				tt.giveRecord(&r)
+				write(t, alice.file("app"), r)
+
+				// act
+				_, err = bob.Acquire(context.Background(), "app", "d2", time.Minute)
+
+				// assert
+				assert.NoError(t, err)
+				r, _ = bob.read("app")
+				assert.Equal(t, "bob@host", r.Owner)
+			})
+		}
+	})
+
+	t.Run("should not remove lock which has replaced stale one found by another deployment", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		alice, bob, carol := newManager(dir, "alice@host", nil), newManager(dir, "bob@host", nil), newManager(dir, "carol@host", nil)
+		_, err := alice.Acquire(context.Background(), "app", "d1", time.Minute)
+		assert.NoError(t, err)
+		stale, _ := alice.read("app")
+		stale.ExpiresAt = time.Now().Add(-time.Second)
+		write(t, alice.file("app"), stale)
+		// bob takes the stale lock over, carol has read the stale lock before
+		_, err = bob.Acquire(context.Background(), "app", "d2", time.Minute)
+		assert.NoError(t, err)
+
+		// act
+		_, err = carol.takeOver("app", stale)
+
+		// assert
+		assert.True(t, errors.Is(err, ErrLocked), "unexpected error: %v", err)
+		assert.Contains(t, err.Error(), "bob@host (deployment d2)")
+		r, _ := bob.read("app")
+		assert.Equal(t, "d2", r.DeploymentID)
+	})
+
+	t.Run("should let a single deployment take over stale lock found by several ones", func(t *testing.T) {
+		for round := 0; round < 20; round++ {
+			// arrange
+			dir := t.TempDir()
+			alice := newManager(dir, "alice@host", nil)
+			_, err := alice.Acquire(context.Background(), "app", "d0", time.Minute)
+			assert.NoError(t, err)
+			r, _ := alice.read("app")
+			r.ExpiresAt = time.Now().Add(-time.Second)
+			write(t, alice.file("app"), r)
+
+			// act
+			var wg sync.WaitGroup
+			errs := make([]error, 8)
+			for i := range errs {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					m := newManager(dir, fmt.Sprintf("user%d@host", i), nil)
+					_, errs[i] = m.Acquire(context.Background(), "app", fmt.Sprintf("d%d", i+1), time.Minute)
+				}(i)
+			}
+			wg.Wait()
+
+			// assert
+			acquired := 0
+			for _, err := range errs {
+				if err == nil {
+					acquired++
+					continue
+				}
+				assert.True(t, errors.Is(err, ErrLocked), "unexpected error: %v", err)
+			}
+			assert.Equal(t, 1, acquired)
+			takeovers, _ := filepath.Glob(alice.file("app") + ".takeover-*")
+			assert.Empty(t, takeovers)
+		}
+	})
+
+	t.Run("should block deployment when remote lock is held by another owner", func(t *testing.T) {
+		// arrange
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+		remote := srv.Client("user").(remitly.Locker)
+		alice, bob := newManager(t.TempDir(), "alice@host", remote), newManager(t.TempDir(), "bob@host", remote)
+		ctx := context.Background()
+
+		// act
+		release, err := alice.Acquire(ctx, "app", "d1", time.Minute)
+		_, blocked := bob.Acquire(ctx, "app", "d2", time.Minute)
+		local, _ := bob.Status(ctx, "app")
+		release()
+		_, held := srv.Lock("app")
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, errors.Is(blocked, ErrLocked), "unexpected error: %v", blocked)
+		assert.Contains(t, blocked.Error(), "remote lock of 'app' is held by alice@host")
+		assert.Len(t, local, 1)
+		assert.Equal(t, ScopeRemote, local[0].Scope)
+		assert.False(t, held)
+	})
+
+	t.Run("should block concurrent deployment of the same owner when remote lock is held", func(t *testing.T) {
+		// arrange
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+		remote := srv.Client("user").(remitly.Locker)
+		// both deployments run as the same user on machines sharing the hostname
+		first, second := newManager(t.TempDir(), "ci@runner", remote), newManager(t.TempDir(), "ci@runner", remote)
+		ctx := context.Background()
+
+		// act
+		release, err := first.Acquire(ctx, "app", "d1", time.Minute)
+		_, blocked := second.Acquire(ctx, "app", "d2", time.Minute)
+		lock, held := srv.Lock("app")
+		release()
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, errors.Is(blocked, ErrLocked), "unexpected error: %v", blocked)
+		assert.Contains(t, blocked.Error(), "ci@runner (deployment d1)")
+		assert.True(t, held)
+		assert.Equal(t, "d1", lock.DeploymentID)
+	})
+}
+
+func TestManagerRelease(t *testing.T) {
+	tests := []struct {
+		name         string
+		giveForce    bool
+		wantReleased int
+		wantErr      error
+	}{
+		{
+			name:         "should refuse to release locks of another owner",
+			giveForce:    false,
+			wantReleased: 0,
+			wantErr:      ErrNotOwner,
+		},
+		{
+			name:         "should release locks of another owner when forced",
+			giveForce:    true,
+			wantReleased: 2,
+			wantErr:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			srv := remitlytest.NewServer()
+			defer srv.Close()
+			remote := srv.Client("user").(remitly.Locker)
+			dir := t.TempDir()
+			alice, bob := newManager(dir, "alice@host", remote), newManager(dir, "bob@host", remote)
+			_, err := alice.Acquire(context.Background(), "app", "d1", time.Minute)
+			assert.NoError(t, err)
+
+			// act
+			released, err := bob.Release(context.Background(), "app", tt.giveForce)
+
+			// assert
+			assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+			assert.Len(t, released, tt.wantReleased)
+			statuses, _ := bob.Status(context.Background(), "app")
+			assert.Len(t, statuses, 2-tt.wantReleased)
+		})
+	}
+}
+
+func write(t *testing.T, file string, r record) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
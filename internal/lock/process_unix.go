@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package lock
+
+import "syscall"
+
+// alive returns true when process with given ID is running on this machine
+func alive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
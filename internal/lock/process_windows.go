@@ -0,0 +1,13 @@
+package lock
+
+import "os"
+
+// alive returns true when process with given ID is running on this machine
+func alive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
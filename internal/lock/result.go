@@ -0,0 +1,38 @@
+package lock
+
+import "time"
+
+// States of a lock, stale locks are replaced by the next deployment
+const (
+	StateHeld  = "held"
+	StateStale = "stale"
+)
+
+// Status describes a lock held on an app
+type Status struct {
+	Scope        string    `json:"scope" yaml:"scope"`
+	App          string    `json:"app" yaml:"app"`
+	Owner        string    `json:"owner" yaml:"owner"`
+	DeploymentID string    `json:"deploymentId,omitempty" yaml:"deploymentId,omitempty"`
+	AcquiredAt   time.Time `json:"acquiredAt" yaml:"acquiredAt"`
+	ExpiresAt    time.Time `json:"expiresAt" yaml:"expiresAt"`
+	State        string    `json:"state" yaml:"state"`
+}
+
+type Statuses []Status
+
+func (s Statuses) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(s))
+	for _, status := range s {
+		rows = append(rows, []string{
+			status.Scope,
+			status.App,
+			status.Owner,
+			status.DeploymentID,
+			status.AcquiredAt.Format(time.RFC3339),
+			status.ExpiresAt.Format(time.RFC3339),
+			status.State,
+		})
+	}
+	return []string{"SCOPE", "APP", "OWNER", "DEPLOYMENT", "ACQUIRED", "EXPIRES", "STATE"}, rows
+}
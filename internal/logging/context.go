@@ -0,0 +1,23 @@
+package logging
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of given context carrying the logger of the context deployed into
+func WithLogger(ctx context.Context, l *log.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the log entry of given context, it is written by the logger carried
+// by the context and by the standard logger when the context does not carry any
+func FromContext(ctx context.Context) *log.Entry {
+	if l, ok := ctx.Value(loggerKey{}).(*log.Logger); ok {
+		return l.WithContext(ctx)
+	}
+	return log.WithContext(ctx)
+}
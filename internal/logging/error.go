@@ -0,0 +1,8 @@
+package logging
+
+import "github.com/pkg/errors"
+
+var (
+	ErrInvalidLevel  = errors.New("log level must be one of: trace, debug, info, warn, error")
+	ErrInvalidFormat = errors.New("log format must be one of: text, json")
+)
@@ -0,0 +1,30 @@
+package logging
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+const requestIDField = "request_id"
+
+// ContextHook adds the request ID carried by the context of a log entry to its fields,
+// so all logs of a single deployment can be correlated
+type ContextHook struct{}
+
+func (ContextHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (ContextHook) Fire(entry *log.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if _, exists := entry.Data[requestIDField]; exists {
+		return nil
+	}
+	if id := remitly.RequestID(entry.Context); id != "" {
+		entry.Data[requestIDField] = id
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package logging
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+
+	levelFlag   = "log-level"
+	formatFlag  = "log-format"
+	fileFlag    = "log-file"
+	verboseFlag = "verbose"
+	quietFlag   = "quiet"
+)
+
+// Settings describes where and how diagnostics are logged
+type Settings struct {
+	Level  string
+	Format string
+	File   string
+}
+
+var (
+	// mu guards the settings below, loggers of contexts may be created concurrently, i.e. by contexts deployed in parallel
+	mu sync.Mutex
+	// explicit holds settings which were specified by flags or environment variables,
+	// those take precedence over the ones specified inside a context
+	explicit Settings
+	// standard holds settings applied to the standard logger
+	standard Settings
+	// files holds log files by their path, loggers writing into the same file share it,
+	// files are kept open until the CLI exits as any logger may still write into them
+	files = map[string]*os.File{}
+)
+
+// AddFlags registers logging flags as persistent flags of given command
+func AddFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String(levelFlag, "", "Log level, one of: trace|debug|info|warn|error (optional, default: info)")
+	cmd.PersistentFlags().String(formatFlag, "", "Log format, one of: text|json (optional, default: text)")
+	cmd.PersistentFlags().String(fileFlag, "", "Write logs into given file instead of stderr (optional)")
+	cmd.PersistentFlags().CountP(verboseFlag, "v", "Increase log verbosity, '-vv' enables trace logs")
+	cmd.PersistentFlags().BoolP(quietFlag, "q", false, "Log errors only")
+}
+
+// Configure applies logging settings from the flags of given command and from
+// 'REMITLY_LOG_LEVEL', 'REMITLY_LOG_FORMAT', 'REMITLY_LOG_FILE' environment variables
+func Configure(cmd *cobra.Command) error {
+	for _, key := range []string{"LOG_LEVEL", "LOG_FORMAT", "LOG_FILE"} {
+		if err := viper.BindEnv(key, "REMITLY_"+key); err != nil {
+			return err
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	explicit = Settings{
+		Level:  viper.GetString("LOG_LEVEL"),
+		Format: viper.GetString("LOG_FORMAT"),
+		File:   viper.GetString("LOG_FILE"),
+	}
+
+	if verbosity, _ := cmd.Flags().GetCount(verboseFlag); verbosity == 1 {
+		explicit.Level = log.DebugLevel.String()
+	} else if verbosity > 1 {
+		explicit.Level = log.TraceLevel.String()
+	}
+	if quiet, _ := cmd.Flags().GetBool(quietFlag); quiet {
+		explicit.Level = log.ErrorLevel.String()
+	}
+	if f := cmd.Flag(levelFlag); f != nil && f.Changed {
+		explicit.Level = f.Value.String()
+	}
+	if f := cmd.Flag(formatFlag); f != nil && f.Changed {
+		explicit.Format = f.Value.String()
+	}
+	if f := cmd.Flag(fileFlag); f != nil && f.Changed {
+		explicit.File = f.Value.String()
+	}
+
+	standard = explicit
+	return configure(log.StandardLogger(), explicit)
+}
+
+// ApplyContext applies settings of the context selected by 'REMITLY_PROFILE' environment variable
+// to the standard logger, values already specified by flags or environment variables are not overridden
+func ApplyContext(s Settings) error {
+	mu.Lock()
+	defer mu.Unlock()
+	s = merge(s)
+	if err := configure(log.StandardLogger(), s); err != nil {
+		return err
+	}
+	standard = s
+	return nil
+}
+
+// NewLogger returns the logger of a context with given settings, values already specified by flags
+// or environment variables are not overridden. The standard logger is returned when settings
+// do not differ from its own, otherwise settings are applied to a new logger so contexts
+// deployed at once do not override settings of each other
+func NewLogger(s Settings) (*log.Logger, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	s = merge(s)
+	if s == standard {
+		return log.StandardLogger(), nil
+	}
+	l := log.New()
+	l.AddHook(ContextHook{})
+	if err := configure(l, s); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func merge(s Settings) Settings {
+	if explicit.Level != "" {
+		s.Level = explicit.Level
+	}
+	if explicit.Format != "" {
+		s.Format = explicit.Format
+	}
+	if explicit.File != "" {
+		s.File = explicit.File
+	}
+	return s
+}
+
+func configure(l *log.Logger, s Settings) error {
+	level := log.InfoLevel
+	if s.Level != "" {
+		var err error
+		if level, err = log.ParseLevel(s.Level); err != nil {
+			return errors.Wrapf(ErrInvalidLevel, "'%s'", s.Level)
+		}
+	}
+
+	var formatter log.Formatter
+	switch strings.ToLower(s.Format) {
+	case "", FormatText:
+		formatter = &log.TextFormatter{}
+	case FormatJSON:
+		formatter = &log.JSONFormatter{}
+	default:
+		return errors.Wrapf(ErrInvalidFormat, "'%s'", s.Format)
+	}
+
+	var out io.Writer = os.Stderr
+	if path := os.ExpandEnv(s.File); path != "" {
+		f, err := open(path)
+		if err != nil {
+			return errors.Wrapf(err, "could not open log file: '%s'", s.File)
+		}
+		out = f
+	}
+
+	l.SetLevel(level)
+	l.SetFormatter(formatter)
+	l.SetOutput(out)
+	return nil
+}
+
+// open returns the log file of given path, it is opened once and shared by all loggers writing into it
+func open(path string) (*os.File, error) {
+	if f, exists := files[path]; exists {
+		return f, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, os.FileMode(0644))
+	if err != nil {
+		return nil, err
+	}
+	files[path] = f
+	return f, nil
+}
@@ -0,0 +1,125 @@
+package logging
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLogger(t *testing.T) {
+	t.Run("should return standard logger when settings of the context do not differ", func(t *testing.T) {
+		// arrange
+		defer func() { _ = ApplyContext(Settings{}) }()
+		assert.NoError(t, ApplyContext(Settings{Level: "debug"}))
+
+		// act
+		l, err := NewLogger(Settings{Level: "debug"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, l == log.StandardLogger())
+	})
+
+	t.Run("should not change standard logger when settings of the context differ", func(t *testing.T) {
+		// arrange
+		defer func() { _ = ApplyContext(Settings{}) }()
+		assert.NoError(t, ApplyContext(Settings{}))
+
+		// act
+		l, err := NewLogger(Settings{Level: "trace", Format: FormatJSON, File: filepath.Join(t.TempDir(), "remitly.log")})
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, l != log.StandardLogger())
+		assert.Equal(t, log.TraceLevel, l.GetLevel())
+		assert.IsType(t, &log.JSONFormatter{}, l.Formatter)
+		assert.Equal(t, log.InfoLevel, log.GetLevel())
+		assert.IsType(t, &log.TextFormatter{}, log.StandardLogger().Formatter)
+	})
+
+	t.Run("should share log file between loggers writing into it", func(t *testing.T) {
+		// arrange
+		defer func() { _ = ApplyContext(Settings{}) }()
+		path := filepath.Join(t.TempDir(), "remitly.log")
+		assert.NoError(t, ApplyContext(Settings{File: path}))
+
+		// act
+		l, err := NewLogger(Settings{Level: "debug", File: path})
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, l.Out == log.StandardLogger().Out)
+	})
+
+	t.Run("should keep log file open when standard logger writes into another one", func(t *testing.T) {
+		// arrange
+		defer func() { _ = ApplyContext(Settings{}) }()
+		dir := t.TempDir()
+		l, err := NewLogger(Settings{Level: "debug", File: filepath.Join(dir, "first.log")})
+		assert.NoError(t, err)
+
+		// act
+		err = ApplyContext(Settings{File: filepath.Join(dir, "second.log")})
+
+		// assert
+		assert.NoError(t, err)
+		_, werr := l.Out.Write([]byte("still open\n"))
+		assert.NoError(t, werr)
+	})
+
+	t.Run("should not override settings specified by flags or environment variables", func(t *testing.T) {
+		// arrange
+		mu.Lock()
+		explicit = Settings{Level: "error"}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			explicit = Settings{}
+			mu.Unlock()
+			_ = ApplyContext(Settings{})
+		}()
+
+		// act
+		l, err := NewLogger(Settings{Level: "debug", Format: FormatJSON})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, log.ErrorLevel, l.GetLevel())
+		assert.IsType(t, &log.JSONFormatter{}, l.Formatter)
+	})
+
+	t.Run("should return error when level of the context is invalid", func(t *testing.T) {
+		// act
+		_, err := NewLogger(Settings{Level: "loud"})
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidLevel), err)
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	t.Run("should write entries by the logger carried by the context", func(t *testing.T) {
+		// arrange
+		l := log.New()
+		ctx := WithLogger(context.Background(), l)
+
+		// act
+		entry := FromContext(ctx)
+
+		// assert
+		assert.True(t, entry.Logger == l)
+		assert.True(t, entry.Context == ctx)
+	})
+
+	t.Run("should write entries by the standard logger by default", func(t *testing.T) {
+		// act
+		entry := FromContext(context.Background())
+
+		// assert
+		assert.True(t, entry.Logger == log.StandardLogger())
+	})
+}
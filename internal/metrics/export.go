@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	fileFlag        = "metrics-file"
+	pushgatewayFlag = "metrics-pushgateway"
+
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	// timeout bounds the push of metrics, unless the context specifies its own
+	timeout = 10 * time.Second
+)
+
+// Exporter writes metrics of a command into a file in OpenMetrics text format
+// and/or pushes them to a Pushgateway compatible endpoint
+type Exporter struct {
+	file        string
+	pushgateway string
+	hc          *http.Client
+}
+
+// AddFlags registers metrics export flags of given command
+func AddFlags(cmd *cobra.Command) {
+	cmd.Flags().String(fileFlag, "", "Write metrics of the command into given file in OpenMetrics text format (optional)")
+	cmd.Flags().String(pushgatewayFlag, "", "Push metrics of the command to given Pushgateway, i.e. 'http://localhost:9091' (optional)")
+}
+
+// NewExporter returns Exporter configured by the flags of given command
+func NewExporter(cmd *cobra.Command) Exporter {
+	e := Exporter{hc: &http.Client{Timeout: timeout}}
+	if f := cmd.Flag(fileFlag); f != nil {
+		e.file = f.Value.String()
+	}
+	if f := cmd.Flag(pushgatewayFlag); f != nil {
+		e.pushgateway = f.Value.String()
+	}
+	return e
+}
+
+// WithDefaults returns exporter using given file and pushgateway when they were not specified by flags
+func (e Exporter) WithDefaults(file, pushgateway string) Exporter {
+	if e.file == "" {
+		e.file = file
+	}
+	if e.pushgateway == "" {
+		e.pushgateway = pushgateway
+	}
+	return e
+}
+
+// WithTimeout returns exporter pushing metrics within given timeout, the default one is kept when it is not positive
+func (e Exporter) WithTimeout(d time.Duration) Exporter {
+	if d > 0 {
+		e.hc = &http.Client{Timeout: d}
+	}
+	return e
+}
+
+// Enabled returns true when metrics are exported anywhere
+func (e Exporter) Enabled() bool {
+	return e.file != "" || e.pushgateway != ""
+}
+
+// Export writes metrics of given registry, the pushed metrics replace the ones of given job and grouping labels,
+// grouping labels are key value pairs
+func (e Exporter) Export(ctx context.Context, r *Registry, job string, grouping ...string) error {
+	if e.file != "" {
+		var b bytes.Buffer
+		if err := r.Write(&b, FormatOpenMetrics); err != nil {
+			return err
+		}
+		file := os.ExpandEnv(e.file)
+		if err := os.MkdirAll(filepath.Dir(file), os.FileMode(0755)); err != nil {
+			return errors.Wrapf(err, "could not create directory: '%s'", filepath.Dir(file))
+		}
+		if err := ioutil.WriteFile(file, b.Bytes(), os.FileMode(0644)); err != nil {
+			return errors.Wrapf(err, "could not write into file: '%s'", file)
+		}
+	}
+
+	if e.pushgateway != "" {
+		var b bytes.Buffer
+		if err := r.Write(&b, FormatPrometheus); err != nil {
+			return err
+		}
+		endpoint := pushURL(e.pushgateway, job, grouping)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, &b)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", prometheusContentType)
+		res, err := e.hc.Do(req)
+		if err != nil {
+			return errors.Wrapf(err, "could not push metrics to: '%s'", e.pushgateway)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode/100 != 2 {
+			return errors.Errorf("could not push metrics to: '%s', http status code: '%d'", e.pushgateway, res.StatusCode)
+		}
+	}
+	return nil
+}
+
+// pushURL returns Pushgateway url of given job and grouping labels
+func pushURL(gateway, job string, grouping []string) string {
+	parts := []string{strings.TrimSuffix(gateway, "/"), "metrics", "job", url.PathEscape(job)}
+	for i := 0; i+1 < len(grouping); i += 2 {
+		name, value := grouping[i], grouping[i+1]
+		// Pushgateway requires values which are empty or contain slashes to be base64 encoded
+		if value == "" || strings.Contains(value, "/") {
+			name, value = name+"@base64", base64.RawURLEncoding.EncodeToString([]byte(value))
+			if value == "" {
+				value = "="
+			}
+		}
+		parts = append(parts, url.PathEscape(name), url.PathEscape(value))
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExporter(t *testing.T) {
+	t.Run("should write openmetrics file", func(t *testing.T) {
+		// arrange
+		file := filepath.Join(t.TempDir(), "metrics", "deploy.prom")
+		r := NewRegistry()
+		r.Set("remitly_deploy_duration_seconds", "Duration of the deployment.", 1)
+
+		// act
+		err := Exporter{file: file}.Export(context.Background(), r, "remitly_deploy")
+
+		// assert
+		assert.NoError(t, err)
+		b, _ := ioutil.ReadFile(file)
+		assert.True(t, strings.HasSuffix(string(b), "remitly_deploy_duration_seconds 1\n# EOF\n"), string(b))
+	})
+
+	t.Run("should push metrics replacing the group of the job", func(t *testing.T) {
+		// arrange
+		var method, path, contentType, body string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			method, path, contentType, body = r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type"), string(b)
+		}))
+		defer srv.Close()
+		r := NewRegistry()
+		r.Set("remitly_deploy_duration_seconds", "Duration of the deployment.", 1)
+		e := Exporter{pushgateway: srv.URL + "/", hc: srv.Client()}
+
+		// act
+		err := e.Export(context.Background(), r, "remitly_deploy", "app", "app_1", "context", "eu/prod")
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodPut, method)
+		assert.Equal(t, "/metrics/job/remitly_deploy/app/app_1/context@base64/ZXUvcHJvZA", path)
+		assert.Equal(t, prometheusContentType, contentType)
+		assert.NotContains(t, body, "# EOF")
+	})
+
+	t.Run("should return error when pushgateway rejects metrics", func(t *testing.T) {
+		// arrange
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer srv.Close()
+		e := Exporter{pushgateway: srv.URL, hc: srv.Client()}
+
+		// act
+		err := e.Export(context.Background(), NewRegistry(), "remitly_deploy")
+
+		// assert
+		assert.Error(t, err)
+	})
+
+	t.Run("should bound push by the timeout of the context", func(t *testing.T) {
+		// arrange
+		e := NewExporter(&cobra.Command{})
+
+		// act
+		bounded := e.WithTimeout(3 * time.Second)
+		unbounded := e.WithTimeout(0)
+
+		// assert
+		assert.Equal(t, timeout, e.hc.Timeout)
+		assert.Equal(t, 3*time.Second, bounded.hc.Timeout)
+		assert.Equal(t, timeout, unbounded.hc.Timeout)
+	})
+}
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Format string
+
+const (
+	// FormatOpenMetrics is the OpenMetrics 1.0 text format
+	FormatOpenMetrics = Format("openmetrics")
+	// FormatPrometheus is the Prometheus 0.0.4 text format, accepted by Pushgateway
+	FormatPrometheus = Format("prometheus")
+)
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// DefaultBuckets of histograms, in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds metrics of a single command run
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+type family struct {
+	name, help, typ string
+	buckets         []float64
+	series          map[string]*series
+}
+
+type series struct {
+	labels  []string
+	value   float64
+	count   uint64
+	buckets []uint64
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Set sets value of the gauge, labels are key value pairs
+func (r *Registry) Set(name, help string, value float64, labels ...string) {
+	r.update(name, help, typeGauge, nil, labels, func(s *series) { s.value = value })
+}
+
+// Add adds value to the counter, name of a counter has to end with '_total'
+func (r *Registry) Add(name, help string, value float64, labels ...string) {
+	r.update(name, help, typeCounter, nil, labels, func(s *series) { s.value += value })
+}
+
+// Observe records value in the histogram with DefaultBuckets
+func (r *Registry) Observe(name, help string, value float64, labels ...string) {
+	r.update(name, help, typeHistogram, DefaultBuckets, labels, func(s *series) {
+		s.value += value
+		s.count++
+		for i, bound := range DefaultBuckets {
+			if value <= bound {
+				s.buckets[i]++
+			}
+		}
+	})
+}
+
+// update applies fn to the series of given family and labels, creating them when needed
+func (r *Registry) update(name, help, typ string, buckets []float64, labels []string, fn func(*series)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, exists := r.families[name]
+	if !exists {
+		f = &family{name: name, help: help, typ: typ, buckets: buckets, series: make(map[string]*series)}
+		r.families[name] = f
+	}
+	key := strings.Join(labels, "\xff")
+	s, exists := f.series[key]
+	if !exists {
+		s = &series{labels: labels, buckets: make([]uint64, len(buckets))}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+// Write writes all metrics in given text format, families and series are sorted
+func (r *Registry) Write(w io.Writer, format Format) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+		familyName := f.name
+		if f.typ == typeCounter && format == FormatOpenMetrics {
+			familyName = strings.TrimSuffix(f.name, "_total")
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n", familyName, escapeHelp(f.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", familyName, f.typ)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := f.series[key]
+			if f.typ != typeHistogram {
+				fmt.Fprintf(&b, "%s%s %s\n", f.name, labelSet(s.labels), formatFloat(s.value))
+				continue
+			}
+			for i, bound := range f.buckets {
+				labels := append(append([]string{}, s.labels...), "le", formatFloat(bound))
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, labelSet(labels), s.buckets[i])
+			}
+			labels := append(append([]string{}, s.labels...), "le", "+Inf")
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, labelSet(labels), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", f.name, labelSet(s.labels), formatFloat(s.value))
+			fmt.Fprintf(&b, "%s_count%s %d\n", f.name, labelSet(s.labels), s.count)
+		}
+	}
+	if format == FormatOpenMetrics {
+		b.WriteString("# EOF\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func labelSet(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	registry := func() *Registry {
+		r := NewRegistry()
+		r.Set("remitly_deploy_duration_seconds", "Duration of the deployment.", 1.5, "app", "app_1")
+		r.Add("remitly_deploy_health_polls_total", "Health checks of deployed instances.", 2, "app", "app_1")
+		r.Add("remitly_deploy_health_polls_total", "Health checks of deployed instances.", 1, "app", "app_1")
+		r.Observe("remitly_api_request_duration_seconds", "Latency of calls.", 0.2, "status", "200")
+		r.Observe("remitly_api_request_duration_seconds", "Latency of calls.", 20, "status", "200")
+		return r
+	}
+	histogram := `# HELP remitly_api_request_duration_seconds Latency of calls.
+# TYPE remitly_api_request_duration_seconds histogram
+remitly_api_request_duration_seconds_bucket{status="200",le="0.005"} 0
+remitly_api_request_duration_seconds_bucket{status="200",le="0.01"} 0
+remitly_api_request_duration_seconds_bucket{status="200",le="0.025"} 0
+remitly_api_request_duration_seconds_bucket{status="200",le="0.05"} 0
+remitly_api_request_duration_seconds_bucket{status="200",le="0.1"} 0
+remitly_api_request_duration_seconds_bucket{status="200",le="0.25"} 1
+remitly_api_request_duration_seconds_bucket{status="200",le="0.5"} 1
+remitly_api_request_duration_seconds_bucket{status="200",le="1"} 1
+remitly_api_request_duration_seconds_bucket{status="200",le="2.5"} 1
+remitly_api_request_duration_seconds_bucket{status="200",le="5"} 1
+remitly_api_request_duration_seconds_bucket{status="200",le="10"} 1
+remitly_api_request_duration_seconds_bucket{status="200",le="+Inf"} 2
+remitly_api_request_duration_seconds_sum{status="200"} 20.2
+remitly_api_request_duration_seconds_count{status="200"} 2
+# HELP remitly_deploy_duration_seconds Duration of the deployment.
+# TYPE remitly_deploy_duration_seconds gauge
+remitly_deploy_duration_seconds{app="app_1"} 1.5
+`
+
+	t.Run("should write openmetrics text format", func(t *testing.T) {
+		// arrange
+		var b bytes.Buffer
+
+		// act
+		err := registry().Write(&b, FormatOpenMetrics)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, histogram+`# HELP remitly_deploy_health_polls Health checks of deployed instances.
+# TYPE remitly_deploy_health_polls counter
+remitly_deploy_health_polls_total{app="app_1"} 3
+# EOF
+`, b.String())
+	})
+
+	t.Run("should write prometheus text format", func(t *testing.T) {
+		// arrange
+		var b bytes.Buffer
+
+		// act
+		err := registry().Write(&b, FormatPrometheus)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, histogram+`# HELP remitly_deploy_health_polls_total Health checks of deployed instances.
+# TYPE remitly_deploy_health_polls_total counter
+remitly_deploy_health_polls_total{app="app_1"} 3
+`, b.String())
+	})
+
+	t.Run("should escape label values", func(t *testing.T) {
+		// arrange
+		var b bytes.Buffer
+		r := NewRegistry()
+		r.Set("gauge", "help", 1, "endpoint", `/a"b\c`)
+
+		// act
+		_ = r.Write(&b, FormatPrometheus)
+
+		// assert
+		assert.Contains(t, b.String(), `gauge{endpoint="/a\"b\\c"} 1`)
+	})
+}
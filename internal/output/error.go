@@ -0,0 +1,8 @@
+package output
+
+import "github.com/pkg/errors"
+
+var (
+	ErrUnsupportedFormat    = errors.New("value of --output flag must be one of: table, json, jsonl, yaml, template")
+	ErrTemplateNotSpecified = errors.New("--template flag has to be specified when using '--output template'")
+)
@@ -0,0 +1,124 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type Format string
+
+const (
+	FormatTable = Format("table")
+	FormatJSON  = Format("json")
+	// FormatJSONLines prints every result as a single line of json, suited for streams of results
+	FormatJSONLines = Format("jsonl")
+	FormatYAML      = Format("yaml")
+	FormatTemplate  = Format("template")
+)
+
+const (
+	outputFlag   = "output"
+	templateFlag = "template"
+)
+
+// Tabular is implemented by results which know how to render themselves as a table
+type Tabular interface {
+	// Table returns header and rows of the table
+	Table() ([]string, [][]string)
+}
+
+type Printer struct {
+	format   Format
+	template string
+	w        io.Writer
+}
+
+// AddFlags registers output flags as persistent flags of given command
+func AddFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringP(outputFlag, "o", string(FormatTable), "Output format, one of: table|json|jsonl|yaml|template")
+	cmd.PersistentFlags().String(templateFlag, "", "Go template used with '--output template', i.e. '{{ .Code }}'")
+}
+
+// NewPrinter returns Printer configured by the output flags of given command
+func NewPrinter(cmd *cobra.Command) (Printer, error) {
+	p := Printer{format: FormatTable, w: cmd.OutOrStdout()}
+	if f := cmd.Flag(outputFlag); f != nil {
+		p.format = Format(strings.ToLower(f.Value.String()))
+	}
+	if f := cmd.Flag(templateFlag); f != nil {
+		p.template = f.Value.String()
+	}
+
+	switch p.format {
+	case FormatTable, FormatJSON, FormatJSONLines, FormatYAML:
+	case FormatTemplate:
+		if p.template == "" {
+			return Printer{}, ErrTemplateNotSpecified
+		}
+	default:
+		return Printer{}, ErrUnsupportedFormat
+	}
+	return p, nil
+}
+
+// PrintStream writes v as one of a stream of results in the configured format,
+// yaml documents are separated by '---' so the stream can be parsed as a whole
+func (p Printer) PrintStream(v interface{}) error {
+	if p.format == FormatYAML {
+		if _, err := io.WriteString(p.w, "---\n"); err != nil {
+			return err
+		}
+	}
+	return p.Print(v)
+}
+
+// Print writes v in the configured format
+func (p Printer) Print(v interface{}) error {
+	switch p.format {
+	case FormatJSON:
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case FormatJSONLines:
+		return json.NewEncoder(p.w).Encode(v)
+	case FormatYAML:
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = p.w.Write(b)
+		return err
+	case FormatTemplate:
+		t, err := template.New("output").Parse(p.template)
+		if err != nil {
+			return err
+		}
+		if err := t.Execute(p.w, v); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(p.w)
+		return err
+	default:
+		t, ok := v.(Tabular)
+		if !ok {
+			_, err := fmt.Fprintf(p.w, "%+v\n", v)
+			return err
+		}
+		header, rows := t.Table()
+		tw := tabwriter.NewWriter(p.w, 0, 0, 3, ' ', 0)
+		if len(header) > 0 {
+			fmt.Fprintln(tw, strings.Join(header, "\t"))
+		}
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+}
@@ -0,0 +1,125 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+type result struct {
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+func (r result) Table() ([]string, [][]string) {
+	return []string{"NAME", "COUNT"}, [][]string{{r.Name, "1"}}
+}
+
+func TestPrinter(t *testing.T) {
+	tests := []struct {
+		name     string
+		giveArgs []string
+		wantOut  string
+		wantErr  error
+	}{
+		{
+			name:     "should print table by default",
+			giveArgs: []string{},
+			wantOut:  "NAME   COUNT\napp    1\n",
+		},
+		{
+			name:     "should print json",
+			giveArgs: []string{"-o", "json"},
+			wantOut:  "{\n  \"name\": \"app\",\n  \"count\": 1\n}\n",
+		},
+		{
+			name:     "should print json lines",
+			giveArgs: []string{"-o", "jsonl"},
+			wantOut:  "{\"name\":\"app\",\"count\":1}\n",
+		},
+		{
+			name:     "should print yaml",
+			giveArgs: []string{"--output", "yaml"},
+			wantOut:  "name: app\ncount: 1\n",
+		},
+		{
+			name:     "should execute go template",
+			giveArgs: []string{"-o", "template", "--template", "{{ .Name }}={{ .Count }}"},
+			wantOut:  "app=1\n",
+		},
+		{
+			name:     "should return error when template is missing",
+			giveArgs: []string{"-o", "template"},
+			wantErr:  ErrTemplateNotSpecified,
+		},
+		{
+			name:     "should return error when format is not supported",
+			giveArgs: []string{"-o", "xml"},
+			wantErr:  ErrUnsupportedFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			var out bytes.Buffer
+			cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+			AddFlags(cmd)
+			cmd.SetOut(&out)
+			assert.NoError(t, cmd.ParseFlags(tt.giveArgs))
+
+			// act
+			p, err := NewPrinter(cmd)
+			if err == nil {
+				err = p.Print(result{Name: "app", Count: 1})
+			}
+
+			// assert
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantOut, out.String())
+		})
+	}
+}
+
+func TestPrinterPrintStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		giveArgs []string
+		wantOut  string
+	}{
+		{
+			name:     "should separate yaml documents",
+			giveArgs: []string{"-o", "yaml"},
+			wantOut:  "---\nname: app\ncount: 1\n---\nname: lb\ncount: 2\n",
+		},
+		{
+			name:     "should print json lines as is",
+			giveArgs: []string{"-o", "jsonl"},
+			wantOut:  "{\"name\":\"app\",\"count\":1}\n{\"name\":\"lb\",\"count\":2}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			var out bytes.Buffer
+			cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+			AddFlags(cmd)
+			cmd.SetOut(&out)
+			assert.NoError(t, cmd.ParseFlags(tt.giveArgs))
+			p, err := NewPrinter(cmd)
+			assert.NoError(t, err)
+
+			// act
+			errApp := p.PrintStream(result{Name: "app", Count: 1})
+			errLB := p.PrintStream(result{Name: "lb", Count: 2})
+
+			// assert
+			assert.NoError(t, errApp)
+			assert.NoError(t, errLB)
+			assert.Equal(t, tt.wantOut, out.String())
+		})
+	}
+}
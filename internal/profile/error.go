@@ -0,0 +1,16 @@
+package profile
+
+import "github.com/pkg/errors"
+
+var (
+	ErrPathVariableNotSet        = errors.New("REMITLY_PATH environment variable not set")
+	ErrProfileVariableNotSet     = errors.New("REMITLY_PROFILE environment variable not set")
+	ErrInvalidContextsFileSyntax = errors.New("invalid $REMITLY_PATH/*.yml file syntax")
+	ErrProfileNotFound           = errors.New("profile $REMITLY_PROFILE was not found inside $REMITLY_PATH/*.yml file")
+
+	ErrInvalidCAFile               = errors.New("tls.ca_file does not contain any PEM encoded certificate")
+	ErrIncompleteClientCertificate = errors.New("both tls.cert_file and tls.key_file have to be specified")
+	ErrInvalidURL                  = errors.New("http.url has to be an absolute http or https url")
+	ErrTLSRequiresHTTPS            = errors.New("tls settings require http.url with https scheme")
+	ErrInvalidProxyURL             = errors.New("http.proxy has to be an absolute http, https or socks5 url")
+)
@@ -0,0 +1,560 @@
+package profile
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/mazxaxz/remitly-cli/internal/hook"
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+	"github.com/mazxaxz/remitly-cli/internal/webhook"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+const defaultLoadBalancerTemplate = "{{ .App }}-lb"
+
+// UserAgent identifies the CLI in calls to the cloud, the root command sets it including its version
+var UserAgent = remitly.DefaultUserAgent
+
+type Context struct {
+	name         string
+	http         httpSpec
+	log          logSpec
+	loadBalancer loadBalancerSpec
+	tls          tlsSpec
+	metrics      metricsSpec
+	webhooks     []webhook.Target
+	hooks        hooksSpec
+	remoteLock   bool
+}
+
+type hooksSpec struct {
+	commands          map[hook.Kind]string
+	rollbackOnFailure bool
+}
+
+type metricsSpec struct {
+	file        string
+	pushgateway string
+}
+
+type httpSpec struct {
+	url       string
+	username  string
+	timeout   time.Duration
+	proxy     string
+	userAgent string
+	headers   map[string]string
+}
+
+type logSpec struct {
+	level  string
+	format string
+	file   string
+}
+
+type loadBalancerSpec struct {
+	name   string
+	create string
+	apps   map[string]string
+}
+
+// Current returns the context selected by 'REMITLY_PROFILE' environment variable,
+// settings have to be loaded before
+func Current() (Context, error) {
+	profile := viper.GetString("PROFILE")
+	if profile == "" {
+		return Context{}, ErrProfileVariableNotSet
+	}
+	return From(viper.AllSettings(), profile)
+}
+
+// Named returns the context with given name regardless of 'REMITLY_PROFILE' environment variable,
+// i.e. one of several contexts deployed into at once. Settings have to be loaded before
+func Named(name string) (Context, error) {
+	return From(viper.AllSettings(), name)
+}
+
+// Name returns the name of the context
+func (pc Context) Name() string {
+	return pc.name
+}
+
+// Logger returns the logger writing logs of the context, logs of several contexts deployed at once
+// are written according to logging settings of each of them
+func (pc Context) Logger() (*log.Logger, error) {
+	return logging.NewLogger(pc.logSettings())
+}
+
+func (pc Context) logSettings() logging.Settings {
+	return logging.Settings{Level: pc.log.level, Format: pc.log.format, File: pc.log.file}
+}
+
+// NewClient returns remitly client configured by the context, given options are applied last
+func (pc Context) NewClient(extra ...remitly.Option) (remitly.Clienter, error) {
+	u, err := url.Parse(pc.http.url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse: '%s' url", pc.http.url)
+	}
+	tlsConfig, err := pc.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := pc.proxyURL()
+	if err != nil {
+		return nil, err
+	}
+
+	logger, err := pc.Logger()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []remitly.Option{
+		remitly.WithTimeout(pc.http.timeout),
+		remitly.WithTLS(tlsConfig),
+		remitly.WithProxy(proxy),
+		remitly.WithHeaders(pc.headers()),
+		remitly.WithUserAgent(pc.userAgent()),
+		remitly.WithLogger(logger),
+	}
+	opts = append(opts, remitly.CassettesFromEnv()...)
+	opts = append(opts, extra...)
+	return remitly.NewClient(u, pc.http.username, opts...), nil
+}
+
+// proxyURL returns the proxy of the context, nil when proxy environment variables should be used
+func (pc Context) proxyURL() (*url.URL, error) {
+	if pc.http.proxy == "" {
+		return nil, nil
+	}
+	proxy := os.ExpandEnv(pc.http.proxy)
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+		// the url may contain credentials, it is not included in the error
+		return nil, ErrInvalidProxyURL
+	}
+	return u, nil
+}
+
+func (pc Context) headers() http.Header {
+	headers := make(http.Header, len(pc.http.headers))
+	for key, value := range pc.http.headers {
+		headers.Set(key, os.ExpandEnv(value))
+	}
+	return headers
+}
+
+func (pc Context) userAgent() string {
+	if pc.http.userAgent == "" {
+		return UserAgent
+	}
+	return pc.http.userAgent + " " + UserAgent
+}
+
+// Validate checks settings of the context which are not verified while parsing it
+func (pc Context) Validate() error {
+	u, err := url.Parse(pc.http.url)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.Wrapf(ErrInvalidURL, "'%s'", pc.http.url)
+	}
+	if !pc.tls.empty() && u.Scheme != "https" {
+		return ErrTLSRequiresHTTPS
+	}
+	if _, err := pc.proxyURL(); err != nil {
+		return err
+	}
+	_, err = pc.TLSConfig()
+	return err
+}
+
+// LoadBalancer returns the name of the load balancer serving given app,
+// name templates of contexts[].load_balancer are executed with '.App' and '.Context' fields
+func (pc Context) LoadBalancer(app string) (string, error) {
+	text := defaultLoadBalancerTemplate
+	if pc.loadBalancer.name != "" {
+		text = pc.loadBalancer.name
+	}
+	if name, exists := pc.loadBalancer.apps[app]; exists {
+		text = name
+	}
+
+	t, err := template.New("load_balancer").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid load balancer name template: '%s'", text)
+	}
+	var b bytes.Buffer
+	data := map[string]string{"App": app, "Context": pc.name}
+	if err := t.Execute(&b, data); err != nil {
+		return "", errors.Wrapf(err, "could not execute load balancer name template: '%s'", text)
+	}
+	return b.String(), nil
+}
+
+// Metrics returns the file and the Pushgateway metrics are exported to, empty when not specified
+func (pc Context) Metrics() (string, string) {
+	return pc.metrics.file, pc.metrics.pushgateway
+}
+
+// HTTPTimeout returns the timeout of a single call made on behalf of the context, zero when not specified
+func (pc Context) HTTPTimeout() time.Duration {
+	return pc.http.timeout
+}
+
+// Webhooks returns targets deployment events are posted to, environment variables are expanded in their urls and secrets
+func (pc Context) Webhooks() []webhook.Target {
+	targets := make([]webhook.Target, 0, len(pc.webhooks))
+	for _, t := range pc.webhooks {
+		targets = append(targets, webhook.Target{URL: os.ExpandEnv(t.URL), Secret: os.ExpandEnv(t.Secret), Events: t.Events})
+	}
+	return targets
+}
+
+// Hooks returns commands run around deployments by their kind and whether their failure rolls back the deployment
+func (pc Context) Hooks() (map[hook.Kind]string, bool) {
+	return pc.hooks.commands, pc.hooks.rollbackOnFailure
+}
+
+// RemoteLock returns true when deployments lock apps through lock endpoints of the cloud as well
+func (pc Context) RemoteLock() bool {
+	return pc.remoteLock
+}
+
+// CreateLoadBalancer returns the load balancer creation policy of the context, empty when not specified
+func (pc Context) CreateLoadBalancer() string {
+	return pc.loadBalancer.create
+}
+
+// Names returns names of all contexts inside given settings in order of appearance,
+// entries without a name are omitted
+func Names(source map[string]interface{}) ([]string, error) {
+	contexts, ok := source["contexts"].([]interface{})
+	if !ok {
+		return nil, ErrInvalidContextsFileSyntax
+	}
+	names := make([]string, 0, len(contexts))
+	for _, ctx := range contexts {
+		ctxMap, ok := ctx.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		if name, ok := ctxMap["name"].(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func From(source map[string]interface{}, profile string) (Context, error) {
+	var contexts []interface{}
+	if val, exists := source["contexts"]; exists {
+		var ok bool
+		contexts, ok = val.([]interface{})
+		if !ok {
+			return Context{}, ErrInvalidContextsFileSyntax
+		}
+	} else {
+		return Context{}, ErrInvalidContextsFileSyntax
+	}
+
+	// wanted to use viper.Get("contexts.0.name") etc, but accessing
+	// nested values through arrays does not work as it should
+	for _, ctx := range contexts {
+		if ctxMap, ok := ctx.(map[interface{}]interface{}); ok {
+			var pc Context
+			if val, exists := ctxMap["name"]; exists {
+				if pc.name, ok = val.(string); ok {
+					if pc.name != profile {
+						continue
+					}
+				} else {
+					log.WithField("context", ctx).Warn("context name has to be a string, entry skipped")
+					continue
+				}
+			} else {
+				log.WithField("context", ctx).Warn("context name does not exist, entry skipped")
+				continue
+			}
+
+			pc.http = httpSpec{}
+			if val, exists := ctxMap["http"]; exists {
+				if httpMap, ok := val.(map[interface{}]interface{}); ok {
+
+					if v, exists := httpMap["url"]; exists {
+						if pc.http.url, ok = v.(string); !ok {
+							log.WithField("context", ctx).Warn("contexts[].http.url has to be a string, entry skipped")
+							continue
+						}
+					} else {
+						log.WithField("context", ctx).Warn("contexts[].http.url was not specified, entry skipped")
+						continue
+					}
+
+					if v, exists := httpMap["username"]; exists {
+						if pc.http.username, ok = v.(string); !ok {
+							log.WithField("context", ctx).Warn("contexts[].http.username has to be a string, entry skipped")
+							continue
+						}
+					} else {
+						log.WithField("context", ctx).Warn("contexts[].http.username was not specified, entry skipped")
+						continue
+					}
+
+					var timeout string
+					if !optionalString(httpMap, "timeout", &timeout) {
+						log.WithField("context", ctx).Warn("contexts[].http.timeout has to be a string, entry skipped")
+						continue
+					}
+					if timeout != "" {
+						d, err := time.ParseDuration(timeout)
+						if err != nil || d <= 0 {
+							log.WithField("context", ctx).Warn("contexts[].http.timeout has to be a positive duration, e.g. '10s', entry skipped")
+							continue
+						}
+						pc.http.timeout = d
+					}
+
+					if !optionalString(httpMap, "proxy", &pc.http.proxy) ||
+						!optionalString(httpMap, "user_agent", &pc.http.userAgent) {
+						log.WithField("context", ctx).Warn("contexts[].http.proxy and contexts[].http.user_agent have to be strings, entry skipped")
+						continue
+					}
+
+					if v, exists := httpMap["headers"]; exists {
+						headersMap, ok := v.(map[interface{}]interface{})
+						if !ok {
+							log.WithField("context", ctx).Warn("contexts[].http.headers has invalid syntax, entry skipped")
+							continue
+						}
+						pc.http.headers = make(map[string]string, len(headersMap))
+						for key, value := range headersMap {
+							k, ok := key.(string)
+							v, ok2 := value.(string)
+							if !ok || !ok2 {
+								log.WithField("context", ctx).Warn("contexts[].http.headers values have to be strings, entry skipped")
+								break
+							}
+							pc.http.headers[k] = v
+						}
+						if len(pc.http.headers) != len(headersMap) {
+							continue
+						}
+					}
+				} else {
+					log.WithField("context", ctx).Warn("contexts[].http has invalid syntax, entry skipped")
+					continue
+				}
+			} else {
+				log.WithField("context", ctx).Warn("contexts[].http was not specified, entry skipped")
+				continue
+			}
+
+			pc.log = logSpec{}
+			if val, exists := ctxMap["log"]; exists {
+				logMap, ok := val.(map[interface{}]interface{})
+				if !ok {
+					log.WithField("context", ctx).Warn("contexts[].log has invalid syntax, entry skipped")
+					continue
+				}
+				if !optionalString(logMap, "level", &pc.log.level) ||
+					!optionalString(logMap, "format", &pc.log.format) ||
+					!optionalString(logMap, "file", &pc.log.file) {
+					log.WithField("context", ctx).Warn("contexts[].log values have to be strings, entry skipped")
+					continue
+				}
+			}
+
+			pc.tls = tlsSpec{}
+			if val, exists := ctxMap["tls"]; exists {
+				tlsMap, ok := val.(map[interface{}]interface{})
+				if !ok {
+					log.WithField("context", ctx).Warn("contexts[].tls has invalid syntax, entry skipped")
+					continue
+				}
+				if !optionalString(tlsMap, "ca_file", &pc.tls.caFile) ||
+					!optionalString(tlsMap, "cert_file", &pc.tls.certFile) ||
+					!optionalString(tlsMap, "key_file", &pc.tls.keyFile) ||
+					!optionalString(tlsMap, "server_name", &pc.tls.serverName) {
+					log.WithField("context", ctx).Warn("contexts[].tls file and server name values have to be strings, entry skipped")
+					continue
+				}
+				if !optionalBool(tlsMap, "insecure_skip_verify", &pc.tls.insecureSkipVerify) {
+					log.WithField("context", ctx).Warn("contexts[].tls.insecure_skip_verify has to be a boolean, entry skipped")
+					continue
+				}
+			}
+
+			pc.metrics = metricsSpec{}
+			if val, exists := ctxMap["metrics"]; exists {
+				metricsMap, ok := val.(map[interface{}]interface{})
+				if !ok {
+					log.WithField("context", ctx).Warn("contexts[].metrics has invalid syntax, entry skipped")
+					continue
+				}
+				if !optionalString(metricsMap, "file", &pc.metrics.file) ||
+					!optionalString(metricsMap, "pushgateway", &pc.metrics.pushgateway) {
+					log.WithField("context", ctx).Warn("contexts[].metrics values have to be strings, entry skipped")
+					continue
+				}
+			}
+
+			pc.webhooks = nil
+			if val, exists := ctxMap["webhooks"]; exists {
+				targets, ok := parseWebhooks(val)
+				if !ok {
+					log.WithField("context", ctx).Warn("contexts[].webhooks has to be an array of objects with string 'url', 'secret' and 'events' array, entry skipped")
+					continue
+				}
+				pc.webhooks = targets
+			}
+
+			pc.hooks = hooksSpec{}
+			if val, exists := ctxMap["hooks"]; exists {
+				hooks, ok := parseHooks(val)
+				if !ok {
+					log.WithField("context", ctx).Warn("contexts[].hooks commands have to be strings and rollback_on_failure a boolean, entry skipped")
+					continue
+				}
+				pc.hooks = hooks
+			}
+
+			pc.remoteLock = false
+			if val, exists := ctxMap["lock"]; exists {
+				lockMap, ok := val.(map[interface{}]interface{})
+				if !ok || !optionalBool(lockMap, "remote", &pc.remoteLock) {
+					log.WithField("context", ctx).Warn("contexts[].lock.remote has to be a boolean, entry skipped")
+					continue
+				}
+			}
+
+			pc.loadBalancer = loadBalancerSpec{}
+			if val, exists := ctxMap["load_balancer"]; exists {
+				lbMap, ok := val.(map[interface{}]interface{})
+				if !ok {
+					log.WithField("context", ctx).Warn("contexts[].load_balancer has invalid syntax, entry skipped")
+					continue
+				}
+				if !optionalString(lbMap, "name", &pc.loadBalancer.name) ||
+					!optionalString(lbMap, "create", &pc.loadBalancer.create) {
+					log.WithField("context", ctx).Warn("contexts[].load_balancer values have to be strings, entry skipped")
+					continue
+				}
+				if v, exists := lbMap["apps"]; exists {
+					appsMap, ok := v.(map[interface{}]interface{})
+					if !ok {
+						log.WithField("context", ctx).Warn("contexts[].load_balancer.apps has invalid syntax, entry skipped")
+						continue
+					}
+					pc.loadBalancer.apps = make(map[string]string, len(appsMap))
+					for app, name := range appsMap {
+						appName, ok := app.(string)
+						lbName, ok2 := name.(string)
+						if !ok || !ok2 {
+							log.WithField("context", ctx).Warn("contexts[].load_balancer.apps values have to be strings, entry skipped")
+							break
+						}
+						pc.loadBalancer.apps[appName] = lbName
+					}
+					if len(pc.loadBalancer.apps) != len(appsMap) {
+						continue
+					}
+				}
+			}
+
+			return pc, nil
+		}
+	}
+	return Context{}, ErrProfileNotFound
+}
+
+// parseWebhooks reads contexts[].webhooks, returns false when its syntax is invalid
+func parseWebhooks(val interface{}) ([]webhook.Target, bool) {
+	items, ok := val.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	targets := make([]webhook.Target, 0, len(items))
+	for _, item := range items {
+		itemMap, ok := item.(map[interface{}]interface{})
+		if !ok {
+			return nil, false
+		}
+		var t webhook.Target
+		if !optionalString(itemMap, "url", &t.URL) || !optionalString(itemMap, "secret", &t.Secret) || t.URL == "" {
+			return nil, false
+		}
+		if v, exists := itemMap["events"]; exists {
+			events, ok := v.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			for _, e := range events {
+				event, ok := e.(string)
+				if !ok {
+					return nil, false
+				}
+				t.Events = append(t.Events, event)
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets, true
+}
+
+// parseHooks reads contexts[].hooks, keys are kinds of hooks with underscores, returns false when its syntax is invalid
+func parseHooks(val interface{}) (hooksSpec, bool) {
+	hooksMap, ok := val.(map[interface{}]interface{})
+	if !ok {
+		return hooksSpec{}, false
+	}
+	spec := hooksSpec{commands: make(map[hook.Kind]string)}
+	for _, kind := range hook.Kinds() {
+		var command string
+		if !optionalString(hooksMap, strings.ReplaceAll(string(kind), "-", "_"), &command) {
+			return hooksSpec{}, false
+		}
+		if command != "" {
+			spec.commands[kind] = command
+		}
+	}
+	if !optionalBool(hooksMap, "rollback_on_failure", &spec.rollbackOnFailure) {
+		return hooksSpec{}, false
+	}
+	return spec, true
+}
+
+// optionalString reads string value of given key into dst,
+// returns false when the value exists but is not a string
+func optionalString(src map[interface{}]interface{}, key string, dst *string) bool {
+	v, exists := src[key]
+	if !exists {
+		return true
+	}
+	s, ok := v.(string)
+	if ok {
+		*dst = s
+	}
+	return ok
+}
+
+// optionalBool reads boolean value of given key into dst,
+// returns false when the value exists but is not a boolean
+func optionalBool(src map[interface{}]interface{}, key string, dst *bool) bool {
+	v, exists := src[key]
+	if !exists {
+		return true
+	}
+	b, ok := v.(bool)
+	if ok {
+		*dst = b
+	}
+	return ok
+}
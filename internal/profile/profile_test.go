@@ -0,0 +1,611 @@
+package profile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/internal/hook"
+	"github.com/mazxaxz/remitly-cli/internal/webhook"
+)
+
+func TestProfileContextFrom(t *testing.T) {
+	tests := []struct {
+		name        string
+		giveSource  map[string]interface{}
+		giveProfile string
+		wantResult  Context
+		wantErr     error
+	}{
+		{
+			name: "should return invalid syntax when no 'contexts' key",
+			giveSource: map[string]interface{}{
+				"invalid": []interface{}{},
+			},
+			giveProfile: "",
+			wantResult:  Context{},
+			wantErr:     ErrInvalidContextsFileSyntax,
+		},
+		{
+			name: "should return invalid syntax when different type of 'contexts' key",
+			giveSource: map[string]interface{}{
+				"invalid": "value",
+			},
+			giveProfile: "",
+			wantResult:  Context{},
+			wantErr:     ErrInvalidContextsFileSyntax,
+		},
+		{
+			name: "should return profile not found when no entries in 'contexts' array",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{},
+			},
+			giveProfile: "",
+			wantResult:  Context{},
+			wantErr:     ErrProfileNotFound,
+		},
+		{
+			name: "should skip invalid syntax profiles",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[string]interface{}{
+						"invalid_key": 123,
+					},
+					map[interface{}]interface{}{
+						"name_1": "default",
+					},
+					map[interface{}]interface{}{
+						"name": "default",
+					},
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url_1":      "something",
+							"username_1": "something_2",
+						},
+					},
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":        "something",
+							"username_1": "something_2",
+						},
+					},
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url_1":    "something",
+							"username": "something_2",
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult:  Context{},
+			wantErr:     ErrProfileNotFound,
+		},
+		{
+			name: "should return populated profile context",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult: Context{
+				name: "default",
+				http: httpSpec{
+					url:      "something",
+					username: "something_2",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "should return first context in order when same name",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+						},
+					},
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something_else",
+							"username": "something_else_2",
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult: Context{
+				name: "default",
+				http: httpSpec{
+					url:      "something",
+					username: "something_2",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "should return profile context with log settings",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+						},
+						"log": map[interface{}]interface{}{
+							"level":  "debug",
+							"format": "json",
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult: Context{
+				name: "default",
+				http: httpSpec{
+					url:      "something",
+					username: "something_2",
+				},
+				log: logSpec{
+					level:  "debug",
+					format: "json",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "should return profile context with call timeout",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+							"timeout":  "5s",
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult: Context{
+				name: "default",
+				http: httpSpec{
+					url:      "something",
+					username: "something_2",
+					timeout:  5 * time.Second,
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "should skip context with invalid call timeout",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+							"timeout":  "forever",
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult:  Context{},
+			wantErr:     ErrProfileNotFound,
+		},
+		{
+			name: "should return profile context with tls settings",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "https://something",
+							"username": "something_2",
+						},
+						"tls": map[interface{}]interface{}{
+							"ca_file":              "ca.pem",
+							"cert_file":            "client.crt",
+							"key_file":             "client.key",
+							"server_name":          "cloud.internal",
+							"insecure_skip_verify": true,
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult: Context{
+				name: "default",
+				http: httpSpec{
+					url:      "https://something",
+					username: "something_2",
+				},
+				tls: tlsSpec{
+					caFile:             "ca.pem",
+					certFile:           "client.crt",
+					keyFile:            "client.key",
+					serverName:         "cloud.internal",
+					insecureSkipVerify: true,
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "should skip context with invalid tls settings",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "https://something",
+							"username": "something_2",
+						},
+						"tls": map[interface{}]interface{}{
+							"insecure_skip_verify": "yes",
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult:  Context{},
+			wantErr:     ErrProfileNotFound,
+		},
+		{
+			name: "should return profile context with proxy, user agent and headers",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":        "something",
+							"username":   "something_2",
+							"proxy":      "http://proxy:3128",
+							"user_agent": "pipeline",
+							"headers": map[interface{}]interface{}{
+								"X-Tenant-Id": "tenant_1",
+							},
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult: Context{
+				name: "default",
+				http: httpSpec{
+					url:       "something",
+					username:  "something_2",
+					proxy:     "http://proxy:3128",
+					userAgent: "pipeline",
+					headers:   map[string]string{"X-Tenant-Id": "tenant_1"},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "should skip context with invalid headers",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+							"headers":  []interface{}{"X-Tenant-Id"},
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult:  Context{},
+			wantErr:     ErrProfileNotFound,
+		},
+		{
+			name: "should return profile context with metrics settings",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+						},
+						"metrics": map[interface{}]interface{}{
+							"file":        "deploy.prom",
+							"pushgateway": "http://localhost:9091",
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult: Context{
+				name: "default",
+				http: httpSpec{
+					url:      "something",
+					username: "something_2",
+				},
+				metrics: metricsSpec{
+					file:        "deploy.prom",
+					pushgateway: "http://localhost:9091",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "should return profile context with webhooks",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+						},
+						"webhooks": []interface{}{
+							map[interface{}]interface{}{
+								"url":    "http://chat-ops/hooks",
+								"secret": "secret",
+								"events": []interface{}{"deployment.failed"},
+							},
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult: Context{
+				name: "default",
+				http: httpSpec{
+					url:      "something",
+					username: "something_2",
+				},
+				webhooks: []webhook.Target{
+					{URL: "http://chat-ops/hooks", Secret: "secret", Events: []string{"deployment.failed"}},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "should skip context with webhook without url",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+						},
+						"webhooks": []interface{}{
+							map[interface{}]interface{}{"secret": "secret"},
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult:  Context{},
+			wantErr:     ErrProfileNotFound,
+		},
+		{
+			name: "should return profile context with hooks",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+						},
+						"hooks": map[interface{}]interface{}{
+							"pre_deploy":          "./migrate.sh",
+							"post_healthy":        "./smoke.sh",
+							"rollback_on_failure": true,
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult: Context{
+				name: "default",
+				http: httpSpec{
+					url:      "something",
+					username: "something_2",
+				},
+				hooks: hooksSpec{
+					commands:          map[hook.Kind]string{hook.PreDeploy: "./migrate.sh", hook.PostHealthy: "./smoke.sh"},
+					rollbackOnFailure: true,
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "should skip context with hook which is not a string",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+						},
+						"hooks": map[interface{}]interface{}{
+							"post_deploy": []interface{}{"./notify.sh"},
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult:  Context{},
+			wantErr:     ErrProfileNotFound,
+		},
+		{
+			name: "should return profile context with remote lock",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+						},
+						"lock": map[interface{}]interface{}{
+							"remote": true,
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult: Context{
+				name: "default",
+				http: httpSpec{
+					url:      "something",
+					username: "something_2",
+				},
+				remoteLock: true,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "should skip context with invalid log settings",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+						},
+						"log": map[interface{}]interface{}{
+							"level": 5,
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult:  Context{},
+			wantErr:     ErrProfileNotFound,
+		},
+		{
+			name: "should return profile context with load balancer settings",
+			giveSource: map[string]interface{}{
+				"contexts": []interface{}{
+					map[interface{}]interface{}{
+						"name": "default",
+						"http": map[interface{}]interface{}{
+							"url":      "something",
+							"username": "something_2",
+						},
+						"load_balancer": map[interface{}]interface{}{
+							"name":   "{{ .App }}-{{ .Context }}",
+							"create": "never",
+							"apps": map[interface{}]interface{}{
+								"legacy": "legacy-balancer",
+							},
+						},
+					},
+				},
+			},
+			giveProfile: "default",
+			wantResult: Context{
+				name: "default",
+				http: httpSpec{
+					url:      "something",
+					username: "something_2",
+				},
+				loadBalancer: loadBalancerSpec{
+					name:   "{{ .App }}-{{ .Context }}",
+					create: "never",
+					apps:   map[string]string{"legacy": "legacy-balancer"},
+				},
+			},
+			wantErr: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := From(tt.giveSource, tt.giveProfile)
+			assert.Equal(t, tt.wantResult, result)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestContextLoadBalancer(t *testing.T) {
+	tests := []struct {
+		name       string
+		giveSpec   loadBalancerSpec
+		giveApp    string
+		wantResult string
+		wantErr    bool
+	}{
+		{
+			name:       "should return default name when not specified",
+			giveSpec:   loadBalancerSpec{},
+			giveApp:    "app",
+			wantResult: "app-lb",
+		},
+		{
+			name:       "should execute name template",
+			giveSpec:   loadBalancerSpec{name: "{{ .App }}-{{ .Context }}"},
+			giveApp:    "app",
+			wantResult: "app-default",
+		},
+		{
+			name: "should prefer per app name",
+			giveSpec: loadBalancerSpec{
+				name: "{{ .App }}-{{ .Context }}",
+				apps: map[string]string{"legacy": "legacy-balancer"},
+			},
+			giveApp:    "legacy",
+			wantResult: "legacy-balancer",
+		},
+		{
+			name:     "should return error when template is invalid",
+			giveSpec: loadBalancerSpec{name: "{{ .App "},
+			giveApp:  "app",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := Context{name: "default", loadBalancer: tt.giveSpec}
+
+			result, err := pc.LoadBalancer(tt.giveApp)
+
+			assert.Equal(t, tt.wantResult, result)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestContextUserAgent(t *testing.T) {
+	t.Run("should prefix cli user agent with the one of the context", func(t *testing.T) {
+		// arrange
+		UserAgent = "remitly-cli/1.0.0"
+		pc := Context{http: httpSpec{userAgent: "pipeline/2"}}
+
+		// act
+		ua := pc.userAgent()
+
+		// assert
+		assert.Equal(t, "pipeline/2 remitly-cli/1.0.0", ua)
+	})
+}
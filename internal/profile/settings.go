@@ -0,0 +1,116 @@
+package profile
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+)
+
+func LoadSettings(_ *cobra.Command, _ []string) error {
+	viper.AutomaticEnv()
+	viper.SetEnvPrefix("REMITLY")
+	viper.AllowEmptyEnv(true)
+
+	if err := viper.BindEnv("PATH"); err != nil {
+		log.WithError(err).Error("could not bind REMITLY_PATH environment variable, make sure it is set")
+		return err
+	}
+	if err := viper.BindEnv("PROFILE"); err != nil {
+		log.WithError(err).Error("could not bind REMITLY_PROFILE environment variable, make sure it is set")
+		return err
+	}
+
+	path := viper.GetString("PATH")
+	if path == "" {
+		log.Info("make sure REMITLY_PATH is set")
+		return ErrPathVariableNotSet
+	}
+
+	fileName, err := contextsFileName(path)
+	if err != nil {
+		return err
+	}
+
+	viper.SetConfigName(fileName)
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath(".")
+	viper.AddConfigPath(path)
+
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			log.Info("make sure $REMITLY_PATH/*.yml files exist")
+			return err
+		}
+		return err
+	}
+	log.Infof("config successfully loaded, using file: '%s'", viper.ConfigFileUsed())
+
+	// logs not tied to any context are written according to the context selected by 'REMITLY_PROFILE',
+	// errors of a missing or invalid context are left to commands using it
+	if profile := viper.GetString("PROFILE"); profile != "" {
+		if pc, err := From(viper.AllSettings(), profile); err == nil {
+			return logging.ApplyContext(pc.logSettings())
+		}
+	}
+	return nil
+}
+
+// ContextsFile returns the path of the contexts file inside 'REMITLY_PATH',
+// '$REMITLY_PATH/contexts.yml' when it does not exist yet
+func ContextsFile() (string, error) {
+	if err := viper.BindEnv("PATH", "REMITLY_PATH"); err != nil {
+		return "", err
+	}
+	path := viper.GetString("PATH")
+	if path == "" {
+		return "", ErrPathVariableNotSet
+	}
+	path = os.ExpandEnv(path)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return filepath.Join(path, "contexts.yml"), nil
+	}
+
+	fileName, err := contextsFileName(path)
+	if err != nil {
+		return "", err
+	}
+	if fileName == "" {
+		fileName = "contexts"
+	}
+	return filepath.Join(path, fileName+".yml"), nil
+}
+
+// Dir returns the path of given directory inside 'REMITLY_PATH', the directory may not exist yet
+func Dir(name string) (string, error) {
+	if err := viper.BindEnv("PATH", "REMITLY_PATH"); err != nil {
+		return "", err
+	}
+	path := viper.GetString("PATH")
+	if path == "" {
+		return "", ErrPathVariableNotSet
+	}
+	return filepath.Join(os.ExpandEnv(path), name), nil
+}
+
+func contextsFileName(path string) (string, error) {
+	var fileName string
+	err := filepath.Walk(os.ExpandEnv(path), func(path string, f fs.FileInfo, err error) error {
+		if err != nil {
+			return errors.Wrapf(err, "an error occured while scanning directory: '%s'", path)
+		}
+		if strings.HasSuffix(f.Name(), ".yml") {
+			fileName = strings.ReplaceAll(f.Name(), ".yml", "")
+			return nil
+		}
+		return nil
+	})
+	return fileName, err
+}
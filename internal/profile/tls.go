@@ -0,0 +1,59 @@
+package profile
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+type tlsSpec struct {
+	caFile             string
+	certFile           string
+	keyFile            string
+	serverName         string
+	insecureSkipVerify bool
+}
+
+func (s tlsSpec) empty() bool {
+	return s == tlsSpec{}
+}
+
+// TLSConfig returns TLS configuration of connections to the cloud, nil when the context does not specify any
+func (pc Context) TLSConfig() (*tls.Config, error) {
+	if pc.tls.empty() {
+		return nil, nil
+	}
+
+	config := tls.Config{
+		ServerName: pc.tls.serverName,
+		// private cloud endpoints may use self-signed certificates, opted in explicitly
+		InsecureSkipVerify: pc.tls.insecureSkipVerify,
+	}
+
+	if pc.tls.caFile != "" {
+		b, err := ioutil.ReadFile(os.ExpandEnv(pc.tls.caFile))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read tls.ca_file: '%s'", pc.tls.caFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, errors.Wrapf(ErrInvalidCAFile, "'%s'", pc.tls.caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if (pc.tls.certFile == "") != (pc.tls.keyFile == "") {
+		return nil, ErrIncompleteClientCertificate
+	}
+	if pc.tls.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(os.ExpandEnv(pc.tls.certFile), os.ExpandEnv(pc.tls.keyFile))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not load tls.cert_file: '%s' and tls.key_file: '%s'", pc.tls.certFile, pc.tls.keyFile)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return &config, nil
+}
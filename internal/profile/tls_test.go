@@ -0,0 +1,182 @@
+package profile
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly/remitlytest"
+)
+
+// writeKeyPair writes self-signed certificate and its key into given directory
+func writeKeyPair(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "remitly"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	_ = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	_ = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestContextTLSConfig(t *testing.T) {
+	t.Run("should return nil when context does not specify tls", func(t *testing.T) {
+		// arrange
+		pc := Context{}
+
+		// act
+		config, err := pc.TLSConfig()
+
+		// assert
+		assert.NoError(t, err)
+		assert.Nil(t, config)
+	})
+
+	t.Run("should return error when ca file has no certificates", func(t *testing.T) {
+		// arrange
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		_ = ioutil.WriteFile(caFile, []byte("not a certificate"), 0600)
+		pc := Context{tls: tlsSpec{caFile: caFile}}
+
+		// act
+		_, err := pc.TLSConfig()
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidCAFile), err)
+	})
+
+	t.Run("should return error when client key is missing", func(t *testing.T) {
+		// arrange
+		certFile, _ := writeKeyPair(t, t.TempDir())
+		pc := Context{tls: tlsSpec{certFile: certFile}}
+
+		// act
+		_, err := pc.TLSConfig()
+
+		// assert
+		assert.Equal(t, ErrIncompleteClientCertificate, err)
+	})
+
+	t.Run("should return config with client certificate and server settings", func(t *testing.T) {
+		// arrange
+		certFile, keyFile := writeKeyPair(t, t.TempDir())
+		pc := Context{tls: tlsSpec{certFile: certFile, keyFile: keyFile, serverName: "cloud.internal", insecureSkipVerify: true}}
+
+		// act
+		config, err := pc.TLSConfig()
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, config.Certificates, 1)
+		assert.Equal(t, "cloud.internal", config.ServerName)
+		assert.True(t, config.InsecureSkipVerify)
+	})
+
+	t.Run("should call cloud with private ca and client certificate", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		certFile, keyFile := writeKeyPair(t, dir)
+		clientCA, _ := ioutil.ReadFile(certFile)
+		clientCAs := x509.NewCertPool()
+		clientCAs.AppendCertsFromPEM(clientCA)
+
+		srv := httptest.NewUnstartedServer(remitlytest.NewCloud())
+		srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+		srv.StartTLS()
+		defer srv.Close()
+		caFile := filepath.Join(dir, "ca.pem")
+		_ = ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600)
+
+		pc := Context{
+			http: httpSpec{url: srv.URL, username: "user"},
+			tls:  tlsSpec{caFile: caFile, certFile: certFile, keyFile: keyFile},
+		}
+
+		// act
+		rc, err := pc.NewClient()
+		assert.NoError(t, err)
+		_, err = rc.ListLoadBalancers(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+	})
+}
+
+func TestContextValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    Context
+		wantErr error
+	}{
+		{
+			name:    "should accept http url without tls",
+			give:    Context{http: httpSpec{url: "http://cloud.remitly.io/"}},
+			wantErr: nil,
+		},
+		{
+			name:    "should reject relative url",
+			give:    Context{http: httpSpec{url: "cloud.remitly.io"}},
+			wantErr: ErrInvalidURL,
+		},
+		{
+			name:    "should reject tls settings with http url",
+			give:    Context{http: httpSpec{url: "http://cloud.remitly.io/"}, tls: tlsSpec{insecureSkipVerify: true}},
+			wantErr: ErrTLSRequiresHTTPS,
+		},
+		{
+			name:    "should reject proxy without scheme",
+			give:    Context{http: httpSpec{url: "http://cloud.remitly.io/", proxy: "proxy:3128"}},
+			wantErr: ErrInvalidProxyURL,
+		},
+		{
+			name:    "should reject incomplete client certificate",
+			give:    Context{http: httpSpec{url: "https://cloud.remitly.io/"}, tls: tlsSpec{keyFile: "client.key"}},
+			wantErr: ErrIncompleteClientCertificate,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+
+			// act
+			err := tt.give.Validate()
+
+			// assert
+			assert.True(t, errors.Is(err, tt.wantErr), err)
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package promote
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/deploy"
+	"github.com/mazxaxz/remitly-cli/internal/history"
+	"github.com/mazxaxz/remitly-cli/internal/lock"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+)
+
+const (
+	version = "1.0.0"
+)
+
+// deployOnly are flags of the deploy command, which do not apply to promotions
+var deployOnly = []string{"revision", "file", "contexts", "sequential", "parallel", "halt-on-failure"}
+
+type cmdContext struct {
+	from, to string
+	confirm  bool
+	// deploy deploys with flags of the deploy command the promotion is built on
+	deploy func(cmd *cobra.Command, t deploy.Target) (deploy.Summary, error)
+}
+
+func NewCmd() *cobra.Command {
+	var c cmdContext
+
+	// a promotion is a deployment into --to context, it takes over flags of the deploy command
+	cmd, deployTarget := deploy.NewTargetCmd()
+	c.deploy = deployTarget
+
+	cmd.Use = "promote"
+	cmd.Version = version
+	cmd.Short = "A subcommand promoting the application between contexts"
+	cmd.Long = `
+A subcommand deploying the revision of the application, which runs
+healthy in --from context, into --to context, i.e. from staging to production.
+The replica count of the source is kept, unless --replica-count is specified.
+Promotions are recorded in histories of both contexts.
+
+Subcommand uses:
+	'REMITLY_PATH' - created by 'remitly initialize ...' (required)
+`
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if err := profile.LoadSettings(cmd, args); err != nil {
+			return err
+		}
+		for _, name := range deployOnly {
+			if cmd.Flag(name).Changed {
+				return ErrConflictingFlags
+			}
+		}
+		if c.from == c.to {
+			return ErrSameContext
+		}
+		return nil
+	}
+	cmd.RunE = c.run
+
+	for _, name := range deployOnly {
+		_ = cmd.Flags().MarkHidden(name)
+	}
+	cmd.Flag("application").Usage = "Application name to be promoted (required)"
+	cmd.MarkFlagRequired("application")
+	cmd.Flag("replica-count").Usage = "The number of instances to deploy (optional, default: number of healthy instances in --from context)"
+	cmd.Flags().StringVar(&c.from, "from", "", "Context to promote the application from (required)")
+	cmd.MarkFlagRequired("from")
+	cmd.Flags().StringVar(&c.to, "to", "", "Context to promote the application into (required)")
+	cmd.MarkFlagRequired("to")
+	cmd.Flags().BoolVar(&c.confirm, "confirm", false, "Asks for confirmation before deploying (optional)")
+
+	return cmd
+}
+
+func (c *cmdContext) run(cmd *cobra.Command, _ []string) (err error) {
+	app := cmd.Flag("application").Value.String()
+	from, err := profile.Named(c.from)
+	if err != nil {
+		return err
+	}
+	// the target is resolved upfront, so nothing is read from the source when it does not exist
+	if _, err := profile.Named(c.to); err != nil {
+		return err
+	}
+	rc, err := from.NewClient()
+	if err != nil {
+		return err
+	}
+	lb, err := from.LoadBalancer(app)
+	if err != nil {
+		return err
+	}
+	revision, replicas, err := source(cmd.Context(), rc, lb)
+	if err != nil {
+		return err
+	}
+	if f := cmd.Flag("replica-count"); f.Changed {
+		if replicas, err = strconv.Atoi(f.Value.String()); err != nil {
+			return err
+		}
+	}
+
+	f := log.Fields{"app": app, "revision": revision, "replicas": replicas, "from": c.from, "to": c.to}
+	log.WithContext(cmd.Context()).WithFields(f).Info("promoting application")
+	if c.confirm {
+		question := fmt.Sprintf("Promote %s %s (%d replicas) from %s to %s?", app, revision, replicas, c.from, c.to)
+		if !confirmed(cmd, question) {
+			return ErrPromotionNotConfirmed
+		}
+	}
+
+	var summary deploy.Summary
+	started := time.Now()
+	defer func() { c.record(cmd.Context(), app, revision, replicas, started, summary.ID, err) }()
+	summary, err = c.deploy(cmd, deploy.Target{Context: c.to, Revision: revision, Replicas: replicas})
+	return err
+}
+
+// confirmed asks the question and reads the answer from the input of the command
+func confirmed(cmd *cobra.Command, question string) bool {
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// record appends the promotion to histories of both contexts, with the outcome of the deployment into the target,
+// id is empty when the deployment has failed before starting
+func (c *cmdContext) record(ctx context.Context, app, revision string, replicas int, started time.Time, id string, err error) {
+	r := history.Record{
+		Kind:       history.KindPromotion,
+		App:        app,
+		Revision:   revision,
+		Replicas:   replicas,
+		Code:       deploy.CodeSuccess.String(),
+		From:       c.from,
+		To:         c.to,
+		Owner:      lock.Owner(),
+		StartedAt:  started,
+		FinishedAt: time.Now(),
+	}
+	target, herr := history.ForContext(c.to)
+	if herr != nil {
+		log.WithContext(ctx).WithError(herr).Warn("could not record promotion")
+		return
+	}
+	deployment, exists, herr := find(target, app, id)
+	if herr != nil {
+		log.WithContext(ctx).WithError(herr).Warn("could not read deployment of the promotion")
+	}
+	if exists {
+		r.ID, r.PreviousRevision, r.LoadBalancer = deployment.ID, deployment.PreviousRevision, deployment.LoadBalancer
+		r.Code, r.RolledBack = deployment.Code, deployment.RolledBack
+	} else if err != nil {
+		r.Code = deploy.CodeError.String()
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	for _, name := range []string{c.from, c.to} {
+		h, herr := history.ForContext(name)
+		if herr != nil {
+			log.WithContext(ctx).WithError(herr).Warn("could not record promotion")
+			continue
+		}
+		r.Context = name
+		if herr := h.Append(r); herr != nil {
+			log.WithContext(ctx).WithError(herr).WithField("context", name).Warn("could not record promotion")
+		}
+	}
+}
+
+// find returns the deployment of the app with given ID from the history, false is returned when there is none
+func find(h history.History, app, id string) (history.Record, bool, error) {
+	if id == "" {
+		return history.Record{}, false, nil
+	}
+	records, err := h.Records(app)
+	if err != nil {
+		return history.Record{}, false, err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Kind == history.KindDeployment && records[i].ID == id {
+			return records[i], true, nil
+		}
+	}
+	return history.Record{}, false, nil
+}
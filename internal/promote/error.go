@@ -0,0 +1,11 @@
+package promote
+
+import "github.com/pkg/errors"
+
+var (
+	ErrSameContext           = errors.New("--from and --to flags must name different contexts")
+	ErrConflictingFlags      = errors.New("revision is read from the --from context, --revision, --file and --contexts flags cannot be used")
+	ErrNoHealthyRevision     = errors.New("application has no healthy instances to promote in the source context")
+	ErrRolloutInProgress     = errors.New("application runs more than one revision in the source context, a rollout is in progress")
+	ErrPromotionNotConfirmed = errors.New("promotion has not been confirmed")
+)
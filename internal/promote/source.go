@@ -0,0 +1,39 @@
+package promote
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+// source returns the revision running behind the load balancer of the source context
+// and the number of its healthy instances, revisions without healthy instances are ignored
+func source(ctx context.Context, rc remitly.Clienter, lbName string) (string, int, error) {
+	instances, err := rc.GetInstances(ctx, lbName)
+	if err != nil && !errors.Is(err, remitly.ErrNotFound) {
+		return "", 0, err
+	}
+
+	healthy := make(map[string]int)
+	for _, instance := range instances {
+		if instance.Status == remitly.StateHealthy {
+			healthy[instance.Version]++
+		}
+	}
+	if len(healthy) > 1 {
+		revisions := make([]string, 0, len(healthy))
+		for revision := range healthy {
+			revisions = append(revisions, revision)
+		}
+		sort.Strings(revisions)
+		return "", 0, errors.Wrapf(ErrRolloutInProgress, "load balancer '%s' runs: %s", lbName, strings.Join(revisions, ", "))
+	}
+	for revision, n := range healthy {
+		return revision, n, nil
+	}
+	return "", 0, errors.Wrapf(ErrNoHealthyRevision, "load balancer '%s'", lbName)
+}
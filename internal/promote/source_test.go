@@ -0,0 +1,88 @@
+package promote
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+	mockRemitly "github.com/mazxaxz/remitly-cli/pkg/remitly/mocks"
+)
+
+func TestSource(t *testing.T) {
+	instance := func(version string, status remitly.State) remitly.Instance {
+		return remitly.Instance{Version: version, Status: status}
+	}
+	tests := []struct {
+		name          string
+		giveInstances []remitly.Instance
+		giveErr       error
+		wantRevision  string
+		wantReplicas  int
+		wantErr       error
+	}{
+		{
+			name: "should return revision with the number of its healthy instances",
+			giveInstances: []remitly.Instance{
+				instance("1.0.0", remitly.StateHealthy),
+				instance("1.0.0", remitly.StateUnhealthy),
+				instance("1.0.0", remitly.StateHealthy),
+			},
+			wantRevision: "1.0.0",
+			wantReplicas: 2,
+		},
+		{
+			name: "should ignore revisions without healthy instances",
+			giveInstances: []remitly.Instance{
+				instance("1.0.0", remitly.StateHealthy),
+				instance("2.0.0", remitly.StateProvisioning),
+				instance("3.0.0", remitly.StateUnhealthy),
+			},
+			wantRevision: "1.0.0",
+			wantReplicas: 1,
+		},
+		{
+			name:          "should return error when a rollout is in progress",
+			giveInstances: []remitly.Instance{instance("1.0.0", remitly.StateHealthy), instance("2.0.0", remitly.StateHealthy)},
+			wantErr:       ErrRolloutInProgress,
+		},
+		{
+			name:          "should return error when no instance is healthy",
+			giveInstances: []remitly.Instance{instance("1.0.0", remitly.StateProvisioning)},
+			wantErr:       ErrNoHealthyRevision,
+		},
+		{
+			name:    "should return error when load balancer does not exist",
+			giveErr: remitly.ErrNotFound,
+			wantErr: ErrNoHealthyRevision,
+		},
+		{
+			name:    "should return error when instances cannot be read",
+			giveErr: remitly.ErrForbidden,
+			wantErr: remitly.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockRemitlyClient := mockRemitly.NewMockClienter(mockCtrl)
+
+			// expected calls
+			mockRemitlyClient.EXPECT().GetInstances(gomock.Any(), "lb_1").Return(tt.giveInstances, tt.giveErr)
+
+			// act
+			revision, replicas, err := source(context.Background(), mockRemitlyClient, "lb_1")
+
+			// assert
+			assert.True(t, errors.Is(err, tt.wantErr), err)
+			assert.Equal(t, tt.wantRevision, revision)
+			assert.Equal(t, tt.wantReplicas, replicas)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package rollout
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/lock"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+)
+
+const (
+	version = "1.0.0"
+)
+
+type cmdContext struct {
+	app    string
+	signal Signal
+}
+
+func NewCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "rollout",
+		Version: version,
+		Short:   "A subcommand for controlling deployments in progress",
+		Long: `
+A subcommand for pausing, resuming and aborting a deployment
+of the application in progress on this machine. The deployment
+picks the signal up between its steps.
+
+Subcommand uses:
+	'REMITLY_PROFILE' - environment variable (optional, default: default)
+	'REMITLY_PATH' - created by 'remitly initialize ...' (required)
+`,
+	}
+
+	cmd.AddCommand(newSignalCmd(Pause, "Holds the deployment at its next step"))
+	cmd.AddCommand(newSignalCmd(Resume, "Continues a paused deployment"))
+	cmd.AddCommand(newSignalCmd(Abort, "Stops the deployment at its next step and rolls it back"))
+
+	return &cmd
+}
+
+func newSignalCmd(signal Signal, short string) *cobra.Command {
+	c := cmdContext{signal: signal}
+	cmd := cobra.Command{
+		Use:   string(signal),
+		Short: short,
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return profile.LoadSettings(cmd, args)
+		},
+		RunE: c.send,
+	}
+	cmd.Flags().StringVarP(&c.app, "application", "a", "", "Application name (required)")
+	cmd.MarkFlagRequired("application")
+	return &cmd
+}
+
+func (c *cmdContext) send(cmd *cobra.Command, _ []string) error {
+	pc, err := profile.Current()
+	if err != nil {
+		return err
+	}
+	// a deployment in progress holds the local lock of the app
+	locks, err := lock.ForContext(pc, nil)
+	if err != nil {
+		return err
+	}
+	statuses, err := locks.Status(cmd.Context(), c.app)
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 || statuses[0].State != lock.StateHeld {
+		return ErrNoRollout
+	}
+
+	control, err := ForContext(pc)
+	if err != nil {
+		return err
+	}
+	if err := control.Send(c.app, c.signal, lock.Owner()); err != nil {
+		return err
+	}
+	f := log.Fields{"app": c.app, "signal": c.signal, "deployment": statuses[0].DeploymentID}
+	log.WithContext(cmd.Context()).WithFields(f).Info("signal sent")
+	return nil
+}
@@ -0,0 +1,141 @@
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+)
+
+type Signal string
+
+const (
+	// Pause holds the rollout at the next pause point
+	Pause = Signal("pause")
+	// Resume continues a paused rollout
+	Resume = Signal("resume")
+	// Abort stops the rollout at the next pause point and rolls it back
+	Abort = Signal("abort")
+)
+
+// pollInterval is the time between reads of the control file of a paused rollout
+var pollInterval = time.Second
+
+// message is the content of a control file
+type message struct {
+	Signal Signal    `json:"signal"`
+	Sender string    `json:"sender"`
+	SentAt time.Time `json:"sentAt"`
+}
+
+// Control passes signals to running deployments through files inside a directory
+type Control struct {
+	dir string
+}
+
+// New returns Control keeping control files inside dir
+func New(dir string) Control {
+	return Control{dir: dir}
+}
+
+// ForContext returns Control keeping control files of the context inside '$REMITLY_PATH/rollouts'
+func ForContext(pc profile.Context) (Control, error) {
+	dir, err := profile.Dir("rollouts")
+	if err != nil {
+		return Control{}, err
+	}
+	return New(filepath.Join(dir, pc.Name())), nil
+}
+
+// Send signals the rollout of the app, the latest signal wins
+func (c Control) Send(app string, signal Signal, sender string) error {
+	if err := os.MkdirAll(c.dir, os.FileMode(0755)); err != nil {
+		return errors.Wrapf(err, "could not create directory: '%s'", c.dir)
+	}
+	b, err := json.Marshal(message{Signal: signal, Sender: sender, SentAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	// the control file is replaced by a complete one, the deployment never reads a partial signal
+	tmp, err := ioutil.TempFile(c.dir, ".control-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.file(app))
+}
+
+// Signal returns the latest signal of the rollout of the app, empty when none was sent
+func (c Control) Signal(app string) (Signal, error) {
+	b, err := ioutil.ReadFile(c.file(app))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var m message
+	if err := json.Unmarshal(b, &m); err != nil {
+		return "", errors.Wrapf(err, "could not parse control file: '%s'", c.file(app))
+	}
+	return m.Signal, nil
+}
+
+// Clear removes signals of the rollout of the app
+func (c Control) Clear(app string) error {
+	if err := os.Remove(c.file(app)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Hold is a pause point of the rollout of the app, it blocks while the rollout is paused
+// and returns true when the rollout was aborted. Unreadable signals are logged and ignored
+func (c Control) Hold(ctx context.Context, app string) bool {
+	paused := false
+	for {
+		signal, err := c.Signal(app)
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Warn("could not read rollout signal")
+		}
+		switch signal {
+		case Abort:
+			logging.FromContext(ctx).WithField("app", app).Warn("rollout aborted")
+			return true
+		case Pause:
+			if !paused {
+				logging.FromContext(ctx).WithField("app", app).Info("rollout paused, resume it with 'remitly rollout resume'")
+				paused = true
+			}
+		default:
+			if paused {
+				logging.FromContext(ctx).WithField("app", app).Info("rollout resumed")
+			}
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (c Control) file(app string) string {
+	return filepath.Join(c.dir, app+".control")
+}
@@ -0,0 +1,106 @@
+package rollout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestControlHold(t *testing.T) {
+	pollInterval = 10 * time.Millisecond
+
+	tests := []struct {
+		name        string
+		giveSignals []Signal
+		wantAborted bool
+		wantHeld    bool
+	}{
+		{
+			name:        "should continue when no signal was sent",
+			giveSignals: nil,
+			wantAborted: false,
+			wantHeld:    false,
+		},
+		{
+			name:        "should hold paused rollout until resumed",
+			giveSignals: []Signal{Pause, Resume},
+			wantAborted: false,
+			wantHeld:    true,
+		},
+		{
+			name:        "should return aborted when paused rollout is aborted",
+			giveSignals: []Signal{Pause, Abort},
+			wantAborted: true,
+			wantHeld:    true,
+		},
+		{
+			name:        "should return aborted",
+			giveSignals: []Signal{Abort},
+			wantAborted: true,
+			wantHeld:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			c := New(t.TempDir())
+			var later []Signal
+			if len(tt.giveSignals) > 0 {
+				assert.NoError(t, c.Send("app", tt.giveSignals[0], "alice@host"))
+				later = tt.giveSignals[1:]
+			}
+			go func() {
+				for _, signal := range later {
+					time.Sleep(100 * time.Millisecond)
+					_ = c.Send("app", signal, "alice@host")
+				}
+			}()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			// act
+			start := time.Now()
+			aborted := c.Hold(ctx, "app")
+
+			// assert
+			assert.Equal(t, tt.wantAborted, aborted)
+			assert.Equal(t, tt.wantHeld, time.Since(start) >= 100*time.Millisecond)
+		})
+	}
+
+	t.Run("should stop holding once context is done", func(t *testing.T) {
+		// arrange
+		c := New(t.TempDir())
+		assert.NoError(t, c.Send("app", Pause, "alice@host"))
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// act
+		aborted := c.Hold(ctx, "app")
+
+		// assert
+		assert.False(t, aborted)
+		assert.Error(t, ctx.Err())
+	})
+}
+
+func TestControlClear(t *testing.T) {
+	t.Run("should remove sent signal", func(t *testing.T) {
+		// arrange
+		c := New(t.TempDir())
+		assert.NoError(t, c.Send("app", Abort, "alice@host"))
+
+		// act
+		err := c.Clear("app")
+
+		// assert
+		assert.NoError(t, err)
+		signal, err := c.Signal("app")
+		assert.NoError(t, err)
+		assert.Equal(t, Signal(""), signal)
+		assert.NoError(t, c.Clear("app"))
+	})
+}
@@ -0,0 +1,7 @@
+package rollout
+
+import "github.com/pkg/errors"
+
+var (
+	ErrNoRollout = errors.New("no deployment of the app is in progress")
+)
@@ -0,0 +1,151 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly/remitlytest"
+)
+
+const (
+	version = "1.0.0"
+)
+
+type cmdContext struct {
+	port         int
+	bootTime     time.Duration
+	failureRate  float64
+	name         string
+	username     string
+	writeContext bool
+}
+
+func NewCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "sandbox",
+		Version: version,
+		Short:   "A subcommand for running a local mock cloud",
+	}
+	cmd.AddCommand(newServeCmd())
+	return &cmd
+}
+
+func newServeCmd() *cobra.Command {
+	var c cmdContext
+	cmd := cobra.Command{
+		Use:   "serve",
+		Short: "Serves a local mock cloud until interrupted",
+		Long: `
+Serves an in-memory mock of the remote cloud on a local port,
+so the deployments can be tried end-to-end without touching
+the real cloud. The state is lost once the command exits.
+
+A context pointing at the mock cloud is added to the contexts file,
+use it by setting 'REMITLY_PROFILE' to its name.
+
+Subcommand uses:
+	'REMITLY_PATH' - contexts file location (required)
+`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			cmd.SilenceUsage = true
+			if c.failureRate < 0 || c.failureRate > 1 {
+				return ErrInvalidFailureRate
+			}
+			return nil
+		},
+		RunE: c.serve,
+	}
+
+	cmd.Flags().IntVarP(&c.port, "port", "p", 8080, "Local port to listen on (optional, default: 8080)")
+	cmd.Flags().DurationVar(&c.bootTime, "boot-time", 5*time.Second, "How long created instances stay provisioning (optional, default: 5s)")
+	cmd.Flags().Float64Var(&c.failureRate, "failure-rate", 0, "Probability, from 0 to 1, of a created instance becoming unhealthy (optional, default: 0)")
+	cmd.Flags().StringVarP(&c.name, "name", "n", "sandbox", "Name of the context added to the contexts file (optional, default: sandbox)")
+	cmd.Flags().StringVar(&c.username, "username", "sandbox", "Username of the context added to the contexts file (optional, default: sandbox)")
+	cmd.Flags().BoolVar(&c.writeContext, "write-context", true, "Add the context to the contexts file (optional, default: true)")
+
+	return &cmd
+}
+
+func (c *cmdContext) serve(cmd *cobra.Command, _ []string) error {
+	// the sandbox runs until interrupted, regardless of the deadline of the command's context
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/", c.port)
+	if c.writeContext {
+		file, err := profile.ContextsFile()
+		if err != nil {
+			return err
+		}
+		added, err := addContext(file, c.name, url, c.username)
+		if err != nil {
+			return err
+		}
+		f := log.Fields{"file": file, "context": c.name}
+		if added {
+			log.WithContext(ctx).WithFields(f).Info("context added to the contexts file")
+		} else {
+			log.WithContext(ctx).WithFields(f).Warn("context already exists in the contexts file, left untouched")
+		}
+	}
+
+	cloud := remitlytest.NewCloud(
+		remitlytest.WithBootTime(c.bootTime),
+		remitlytest.WithFailureRate(c.failureRate),
+	)
+	srv := http.Server{
+		Addr:    fmt.Sprintf("127.0.0.1:%d", c.port),
+		Handler: logRequests(cloud),
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
+	log.WithContext(ctx).WithField("url", url).Infof("sandbox is listening, use REMITLY_PROFILE=%s", c.name)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	log.WithContext(ctx).Info("shutting down the sandbox...")
+	return srv.Shutdown(shutdown)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		rec := statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(&rec, r)
+
+		f := log.Fields{
+			"milliseconds": time.Since(now).Milliseconds(),
+			"method":       r.Method,
+			"path":         r.URL.Path,
+			"status":       rec.status,
+		}
+		log.WithContext(r.Context()).WithFields(f).Info("request handled")
+	})
+}
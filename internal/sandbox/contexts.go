@@ -0,0 +1,70 @@
+package sandbox
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// addContext adds context with given name to the contexts file, creating the file when needed,
+// returns false when a context with given name already exists
+func addContext(file, name, url, username string) (bool, error) {
+	var doc yaml.MapSlice
+	b, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return false, errors.Wrapf(err, "could not read file: '%s'", file)
+	}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return false, errors.Wrapf(err, "could not parse file: '%s'", file)
+	}
+
+	entry := yaml.MapSlice{
+		{Key: "name", Value: name},
+		{Key: "http", Value: yaml.MapSlice{
+			{Key: "url", Value: url},
+			{Key: "username", Value: username},
+		}},
+	}
+
+	found := false
+	for i, item := range doc {
+		if item.Key != "contexts" {
+			continue
+		}
+		found = true
+		contexts, ok := item.Value.([]interface{})
+		if !ok && item.Value != nil {
+			return false, ErrInvalidContextsFile
+		}
+		for _, ctx := range contexts {
+			ctxMap, ok := ctx.(yaml.MapSlice)
+			if !ok {
+				continue
+			}
+			for _, field := range ctxMap {
+				if field.Key == "name" && field.Value == name {
+					return false, nil
+				}
+			}
+		}
+		doc[i].Value = append(contexts, entry)
+	}
+	if !found {
+		doc = append(doc, yaml.MapItem{Key: "contexts", Value: []interface{}{entry}})
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), os.FileMode(0755)); err != nil {
+		return false, errors.Wrapf(err, "could not create directory: '%s'", filepath.Dir(file))
+	}
+	if err := ioutil.WriteFile(file, out, os.FileMode(0644)); err != nil {
+		return false, errors.Wrapf(err, "could not write into file: '%s'", file)
+	}
+	return true, nil
+}
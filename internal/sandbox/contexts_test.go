@@ -0,0 +1,82 @@
+package sandbox
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddContext(t *testing.T) {
+	t.Run("should create contexts file when it does not exist", func(t *testing.T) {
+		// arrange
+		file := filepath.Join(t.TempDir(), "remitly", "contexts.yml")
+
+		// act
+		added, err := addContext(file, "sandbox", "http://127.0.0.1:8080/", "sandbox")
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, added)
+		b, _ := ioutil.ReadFile(file)
+		assert.Equal(t, `contexts:
+- name: sandbox
+  http:
+    url: http://127.0.0.1:8080/
+    username: sandbox
+`, string(b))
+	})
+
+	t.Run("should append context to existing contexts", func(t *testing.T) {
+		// arrange
+		file := filepath.Join(t.TempDir(), "contexts.yml")
+		_ = ioutil.WriteFile(file, []byte(`
+contexts:
+  - name: default
+    http:
+      url: http://cloud.remitly.io/
+      username: user
+`), 0644)
+
+		// act
+		added, err := addContext(file, "sandbox", "http://127.0.0.1:8080/", "sandbox")
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, added)
+		b, _ := ioutil.ReadFile(file)
+		assert.Equal(t, `contexts:
+- name: default
+  http:
+    url: http://cloud.remitly.io/
+    username: user
+- name: sandbox
+  http:
+    url: http://127.0.0.1:8080/
+    username: sandbox
+`, string(b))
+	})
+
+	t.Run("should leave existing context untouched", func(t *testing.T) {
+		// arrange
+		file := filepath.Join(t.TempDir(), "contexts.yml")
+		content := []byte(`
+contexts:
+  - name: sandbox
+    http:
+      url: http://127.0.0.1:9090/
+      username: sandbox
+`)
+		_ = ioutil.WriteFile(file, content, 0644)
+
+		// act
+		added, err := addContext(file, "sandbox", "http://127.0.0.1:8080/", "sandbox")
+
+		// assert
+		assert.NoError(t, err)
+		assert.False(t, added)
+		b, _ := ioutil.ReadFile(file)
+		assert.Equal(t, content, b)
+	})
+}
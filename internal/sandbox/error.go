@@ -0,0 +1,8 @@
+package sandbox
+
+import "github.com/pkg/errors"
+
+var (
+	ErrInvalidFailureRate  = errors.New("value of --failure-rate flag must be between 0 and 1")
+	ErrInvalidContextsFile = errors.New("invalid contexts file syntax, 'contexts' has to be an array")
+)
@@ -0,0 +1,168 @@
+package status
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/history"
+	"github.com/mazxaxz/remitly-cli/internal/lock"
+	"github.com/mazxaxz/remitly-cli/internal/output"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/internal/rollout"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+const (
+	version = "1.0.0"
+)
+
+type cmdContext struct {
+	app          string
+	loadBalancer string
+	printer      output.Printer
+}
+
+func NewCmd() *cobra.Command {
+	var c cmdContext
+
+	cmd := cobra.Command{
+		Use:     "status",
+		Version: version,
+		Short:   "A subcommand summarizing the application",
+		Long: `
+A subcommand summarizing the load balancer of the application:
+versions and their instances by status, the deployment in progress
+and the last deployment recorded on this machine.
+
+Subcommand uses:
+	'REMITLY_PROFILE' - environment variable (optional, default: default)
+	'REMITLY_PATH' - created by 'remitly initialize ...' (required)
+`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if err := profile.LoadSettings(cmd, args); err != nil {
+				return err
+			}
+			p, err := output.NewPrinter(cmd)
+			if err != nil {
+				return err
+			}
+			c.printer = p
+			return nil
+		},
+		RunE: c.run,
+	}
+
+	cmd.Flags().StringVarP(&c.app, "application", "a", "", "Application name (required)")
+	cmd.MarkFlagRequired("application")
+	cmd.Flags().StringVar(&c.loadBalancer, "load-balancer", "", "The name of the load balancer (optional, default: derived from --application)")
+
+	return &cmd
+}
+
+func (c *cmdContext) run(cmd *cobra.Command, _ []string) error {
+	pc, err := profile.Current()
+	if err != nil {
+		return err
+	}
+	rc, err := pc.NewClient()
+	if err != nil {
+		return err
+	}
+	lb := c.loadBalancer
+	if lb == "" {
+		if lb, err = pc.LoadBalancer(c.app); err != nil {
+			return err
+		}
+	}
+
+	s := Status{App: c.app, LoadBalancer: lb, Exists: true, Versions: make([]Version, 0)}
+	instances, err := rc.GetInstances(cmd.Context(), lb)
+	switch {
+	case errors.Is(err, remitly.ErrNotFound):
+		s.Exists = false
+	case err != nil:
+		return err
+	}
+	s.Versions = versions(instances)
+
+	if s.Rollout, err = c.rollout(cmd.Context(), pc, rc); err != nil {
+		return err
+	}
+	h, err := history.ForContext(pc.Name())
+	if err != nil {
+		return err
+	}
+	// promotions are recorded in histories of both contexts, even though the source has not been deployed into
+	last, exists, err := h.Last(c.app, history.KindDeployment, history.KindRollback)
+	if err != nil {
+		return err
+	}
+	if exists {
+		s.LastDeployment = &last
+	}
+	return c.printer.Print(s)
+}
+
+// rollout returns the deployment in progress, which holds a lock of the app
+func (c *cmdContext) rollout(ctx context.Context, pc profile.Context, rc remitly.Clienter) (*Rollout, error) {
+	var remote remitly.Locker
+	if pc.RemoteLock() {
+		remote = lock.Remote(rc)
+	}
+	locks, err := lock.ForContext(pc, remote)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := locks.Status(ctx, c.app)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statuses {
+		if s.State != lock.StateHeld {
+			continue
+		}
+		r := Rollout{Scope: s.Scope, Owner: s.Owner, DeploymentID: s.DeploymentID, Since: s.AcquiredAt}
+		if s.Scope == lock.ScopeLocal {
+			control, err := rollout.ForContext(pc)
+			if err != nil {
+				return nil, err
+			}
+			signal, err := control.Signal(c.app)
+			if err != nil {
+				return nil, err
+			}
+			r.Paused = signal == rollout.Pause
+		}
+		return &r, nil
+	}
+	return nil, nil
+}
+
+// versions counts instances by version and status, in order of appearance of the versions
+func versions(instances []remitly.Instance) []Version {
+	result := make([]Version, 0)
+	index := make(map[string]int)
+	for _, instance := range instances {
+		i, exists := index[instance.Version]
+		if !exists {
+			i = len(result)
+			index[instance.Version] = i
+			result = append(result, Version{Version: instance.Version})
+		}
+		v := &result[i]
+		v.Replicas++
+		switch instance.Status {
+		case remitly.StateProvisioning:
+			v.Provisioning++
+		case remitly.StateHealthy:
+			v.Healthy++
+		case remitly.StateUnhealthy:
+			v.Unhealthy++
+		}
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package status
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/internal/history"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+func TestVersions(t *testing.T) {
+	t.Run("should count instances by version and status in order of appearance", func(t *testing.T) {
+		// arrange
+		instances := []remitly.Instance{
+			{ID: "ins_1", Version: "1", Status: remitly.StateHealthy},
+			{ID: "ins_2", Version: "2", Status: remitly.StateProvisioning},
+			{ID: "ins_3", Version: "1", Status: remitly.StateUnhealthy},
+			{ID: "ins_4", Version: "2", Status: remitly.StateHealthy},
+		}
+
+		// act
+		result := versions(instances)
+
+		// assert
+		assert.Equal(t, []Version{
+			{Version: "1", Replicas: 2, Healthy: 1, Unhealthy: 1},
+			{Version: "2", Replicas: 2, Provisioning: 1, Healthy: 1},
+		}, result)
+	})
+}
+
+func TestStatusTable(t *testing.T) {
+	t.Run("should describe versions, rollout and last deployment", func(t *testing.T) {
+		// arrange
+		at := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+		s := Status{
+			App:            "app",
+			LoadBalancer:   "app-lb",
+			Exists:         true,
+			Versions:       []Version{{Version: "2", Replicas: 3, Healthy: 2, Provisioning: 1}},
+			Rollout:        &Rollout{Owner: "alice@host", DeploymentID: "d2", Since: at, Paused: true},
+			LastDeployment: &history.Record{Revision: "1", Code: "success", Owner: "bob@host", FinishedAt: at},
+		}
+
+		// act
+		header, rows := s.Table()
+
+		// assert
+		assert.Nil(t, header)
+		assert.Equal(t, [][]string{
+			{"APP", "app"},
+			{"LOAD BALANCER", "app-lb"},
+			{"VERSION 2", "3 replicas, 2 healthy, 1 provisioning"},
+			{"ROLLOUT", "in progress by alice@host since 2021-05-01T12:00:00Z, deployment d2, paused"},
+			{"LAST DEPLOYMENT", "1 success by bob@host at 2021-05-01T12:00:00Z"},
+		}, rows)
+	})
+}
@@ -0,0 +1,87 @@
+package status
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mazxaxz/remitly-cli/internal/history"
+)
+
+// Status summarizes the load balancer of an application
+type Status struct {
+	App            string          `json:"app" yaml:"app"`
+	LoadBalancer   string          `json:"loadBalancer" yaml:"loadBalancer"`
+	Exists         bool            `json:"exists" yaml:"exists"`
+	Versions       []Version       `json:"versions" yaml:"versions"`
+	Rollout        *Rollout        `json:"rollout,omitempty" yaml:"rollout,omitempty"`
+	LastDeployment *history.Record `json:"lastDeployment,omitempty" yaml:"lastDeployment,omitempty"`
+}
+
+// Version counts instances of a version by their status
+type Version struct {
+	Version      string `json:"version" yaml:"version"`
+	Replicas     int    `json:"replicas" yaml:"replicas"`
+	Provisioning int    `json:"provisioning" yaml:"provisioning"`
+	Healthy      int    `json:"healthy" yaml:"healthy"`
+	Unhealthy    int    `json:"unhealthy" yaml:"unhealthy"`
+}
+
+// Rollout describes a deployment in progress
+type Rollout struct {
+	Scope        string    `json:"scope" yaml:"scope"`
+	Owner        string    `json:"owner" yaml:"owner"`
+	DeploymentID string    `json:"deploymentId,omitempty" yaml:"deploymentId,omitempty"`
+	Since        time.Time `json:"since" yaml:"since"`
+	Paused       bool      `json:"paused" yaml:"paused"`
+}
+
+func (s Status) Table() ([]string, [][]string) {
+	rows := [][]string{
+		{"APP", s.App},
+		{"LOAD BALANCER", s.LoadBalancer},
+	}
+	if !s.Exists {
+		rows = append(rows, []string{"VERSIONS", "load balancer does not exist"})
+	} else if len(s.Versions) == 0 {
+		rows = append(rows, []string{"VERSIONS", "none"})
+	}
+	for _, v := range s.Versions {
+		rows = append(rows, []string{"VERSION " + v.Version, v.String()})
+	}
+
+	rollout := "none"
+	if r := s.Rollout; r != nil {
+		rollout = fmt.Sprintf("in progress by %s since %s", r.Owner, r.Since.Format(time.RFC3339))
+		if r.DeploymentID != "" {
+			rollout += ", deployment " + r.DeploymentID
+		}
+		if r.Paused {
+			rollout += ", paused"
+		}
+	}
+	rows = append(rows, []string{"ROLLOUT", rollout})
+
+	last := "none"
+	if r := s.LastDeployment; r != nil {
+		last = fmt.Sprintf("%s %s by %s at %s", r.Revision, r.Code, r.Owner, r.FinishedAt.Format(time.RFC3339))
+		if r.RolledBack {
+			last += ", rolled back"
+		}
+	}
+	rows = append(rows, []string{"LAST DEPLOYMENT", last})
+	return nil, rows
+}
+
+func (v Version) String() string {
+	parts := []string{fmt.Sprintf("%d replicas", v.Replicas)}
+	for _, c := range []struct {
+		n     int
+		state string
+	}{{v.Healthy, "healthy"}, {v.Provisioning, "provisioning"}, {v.Unhealthy, "unhealthy"}} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.state))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
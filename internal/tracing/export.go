@@ -0,0 +1,202 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	fileFlag     = "trace-file"
+	endpointFlag = "trace-endpoint"
+
+	serviceName = "remitly-cli"
+	tracesPath  = "/v1/traces"
+
+	// timeout bounds the export of spans, a collector which does not respond does not hold the command
+	timeout = 10 * time.Second
+)
+
+// Exporter sends finished spans in OTLP/HTTP JSON encoding to a file and/or a collector
+type Exporter struct {
+	file     string
+	endpoint string
+	hc       *http.Client
+}
+
+// AddFlags registers span export flags of given command
+func AddFlags(cmd *cobra.Command) {
+	cmd.Flags().String(fileFlag, "", "Append spans of the command to given file as OTLP JSON lines (optional)")
+	cmd.Flags().String(endpointFlag, "", "Export spans of the command to given OTLP/HTTP collector, i.e. 'http://localhost:4318' "+
+		"(optional, default: $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or $OTEL_EXPORTER_OTLP_ENDPOINT)")
+}
+
+// NewExporter returns Exporter configured by the flags of given command and OpenTelemetry environment variables
+func NewExporter(cmd *cobra.Command) Exporter {
+	e := Exporter{hc: &http.Client{Timeout: timeout}}
+	if f := cmd.Flag(fileFlag); f != nil {
+		e.file = f.Value.String()
+	}
+	if f := cmd.Flag(endpointFlag); f != nil {
+		e.endpoint = f.Value.String()
+	}
+	switch {
+	case e.endpoint != "":
+		e.endpoint = strings.TrimSuffix(e.endpoint, "/") + tracesPath
+	case os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "":
+		e.endpoint = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	case os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "":
+		e.endpoint = strings.TrimSuffix(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "/") + tracesPath
+	}
+	return e
+}
+
+// Enabled returns true when spans are exported anywhere
+func (e Exporter) Enabled() bool {
+	return e.file != "" || e.endpoint != ""
+}
+
+// Export sends given spans
+func (e Exporter) Export(ctx context.Context, spans []Span) error {
+	if !e.Enabled() || len(spans) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(encode(spans))
+	if err != nil {
+		return err
+	}
+
+	if e.file != "" {
+		if err := appendLine(e.file, b); err != nil {
+			return err
+		}
+	}
+	if e.endpoint != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		res, err := e.hc.Do(req)
+		if err != nil {
+			return errors.Wrapf(err, "could not export spans to: '%s'", e.endpoint)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode/100 != 2 {
+			return errors.Errorf("could not export spans to: '%s', http status code: '%d'", e.endpoint, res.StatusCode)
+		}
+	}
+	return nil
+}
+
+func appendLine(file string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), os.FileMode(0755)); err != nil {
+		return errors.Wrapf(err, "could not create directory: '%s'", filepath.Dir(file))
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.FileMode(0644))
+	if err != nil {
+		return errors.Wrapf(err, "could not open file: '%s'", file)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return errors.Wrapf(err, "could not write into file: '%s'", file)
+	}
+	return nil
+}
+
+// OTLP JSON encoding of ExportTraceServiceRequest, see opentelemetry-proto
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+const (
+	spanKindInternal = 1
+	statusOK         = 1
+	statusError      = 2
+)
+
+func encode(spans []Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentID,
+			Name:              span.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        attributes(span.Attributes),
+			Status:            otlpStatus{Code: statusOK},
+		}
+		if span.Err != nil {
+			s.Status = otlpStatus{Code: statusError, Message: span.Err.Error()}
+		}
+		encoded = append(encoded, s)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: attributes(map[string]string{"service.name": serviceName})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: serviceName}, Spans: encoded}},
+	}}}
+}
+
+func attributes(src map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(src))
+	for key := range src {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	attrs := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		attrs = append(attrs, otlpAttribute{Key: key, Value: otlpValue{StringValue: src[key]}})
+	}
+	return attrs
+}
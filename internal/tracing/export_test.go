@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func finishedSpans() []Span {
+	tracer := NewTracer()
+	ctx, root := tracer.Start(context.Background(), "deployment")
+	_, child := tracer.Start(ctx, "rollback")
+	child.Finish(errors.New("rollback failed"))
+	root.Finish(nil)
+	return tracer.Spans()
+}
+
+func TestExporter(t *testing.T) {
+	t.Run("should do nothing when not configured", func(t *testing.T) {
+		// arrange
+		e := Exporter{}
+
+		// act
+		err := e.Export(context.Background(), finishedSpans())
+
+		// assert
+		assert.NoError(t, err)
+		assert.False(t, e.Enabled())
+	})
+
+	t.Run("should append spans to file as otlp json lines", func(t *testing.T) {
+		// arrange
+		file := filepath.Join(t.TempDir(), "traces", "spans.jsonl")
+		e := Exporter{file: file}
+
+		// act
+		errFirst := e.Export(context.Background(), finishedSpans())
+		errSecond := e.Export(context.Background(), finishedSpans())
+
+		// assert
+		assert.NoError(t, errFirst)
+		assert.NoError(t, errSecond)
+		b, _ := ioutil.ReadFile(file)
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		assert.Len(t, lines, 2)
+
+		var req otlpRequest
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &req))
+		spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+		assert.Len(t, spans, 2)
+		assert.Equal(t, "rollback", spans[0].Name)
+		assert.Equal(t, otlpStatus{Code: statusError, Message: "rollback failed"}, spans[0].Status)
+		assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+		assert.Equal(t, otlpStatus{Code: statusOK}, spans[1].Status)
+	})
+
+	t.Run("should post spans to collector", func(t *testing.T) {
+		// arrange
+		var path, contentType string
+		var req otlpRequest
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+			_ = json.NewDecoder(r.Body).Decode(&req)
+		}))
+		defer srv.Close()
+		e := Exporter{endpoint: srv.URL + tracesPath, hc: srv.Client()}
+
+		// act
+		err := e.Export(context.Background(), finishedSpans())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, tracesPath, path)
+		assert.Equal(t, "application/json", contentType)
+		assert.Len(t, req.ResourceSpans[0].ScopeSpans[0].Spans, 2)
+	})
+
+	t.Run("should bound export by a timeout", func(t *testing.T) {
+		// act
+		e := NewExporter(&cobra.Command{})
+
+		// assert
+		assert.Equal(t, timeout, e.hc.Timeout)
+	})
+}
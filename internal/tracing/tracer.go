@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+// Span is a single timed operation of a trace
+type Span struct {
+	TraceID    string
+	SpanID     string
+	ParentID   string
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Err        error
+
+	tracer *Tracer
+}
+
+// Tracer records spans of a single trace, its ID identifies the whole operation, e.g. a deployment
+type Tracer struct {
+	mu      sync.Mutex
+	traceID string
+	spans   []*Span
+}
+
+type spanKey struct{}
+
+// NewTracer returns tracer of a new trace
+func NewTracer() *Tracer {
+	return &Tracer{traceID: randomHex(16)}
+}
+
+// TraceID returns ID of the trace, it correlates all deployments of the command
+func (t *Tracer) TraceID() string {
+	return t.traceID
+}
+
+// NewID returns a random ID of an operation traced by the tracer, e.g. a single deployment
+func NewID() string {
+	return randomHex(16)
+}
+
+// Start starts span with given name, child of the span carried by the context, calls made with returned
+// context carry the traceparent of the span and the request ID of the context, the trace ID when it has none
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...string) (context.Context, *Span) {
+	span := Span{
+		TraceID:    t.traceID,
+		SpanID:     randomHex(8),
+		Name:       name,
+		Start:      time.Now(),
+		Attributes: make(map[string]string, len(attrs)/2),
+		tracer:     t,
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		span.ParentID = parent.SpanID
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		span.Attributes[attrs[i]] = attrs[i+1]
+	}
+
+	ctx = context.WithValue(ctx, spanKey{}, &span)
+	if remitly.RequestID(ctx) == "" {
+		ctx = remitly.WithRequestID(ctx, t.traceID)
+	}
+	ctx = remitly.WithTraceParent(ctx, span.TraceParent())
+	return ctx, &span
+}
+
+// Spans returns finished spans in order of finishing
+func (t *Tracer) Spans() []Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans := make([]Span, 0, len(t.spans))
+	for _, span := range t.spans {
+		spans = append(spans, *span)
+	}
+	return spans
+}
+
+// Finish ends the span, the span has failed when err is not nil
+func (s *Span) Finish(err error) {
+	s.End, s.Err = time.Now(), err
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s)
+}
+
+// SetAttribute sets attribute of the span
+func (s *Span) SetAttribute(key, value string) {
+	s.Attributes[key] = value
+}
+
+// TraceParent returns W3C traceparent header value of the span
+func (s *Span) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	// crypto/rand does not fail on supported platforms
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+func TestTracer(t *testing.T) {
+	t.Run("should record child spans of the same trace", func(t *testing.T) {
+		// arrange
+		tracer := NewTracer()
+		errFailed := errors.New("failed")
+
+		// act
+		ctx, root := tracer.Start(context.Background(), "deployment", "app", "app_1")
+		_, child := tracer.Start(ctx, "snapshot")
+		child.Finish(errFailed)
+		root.Finish(nil)
+
+		// assert
+		spans := tracer.Spans()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, "snapshot", spans[0].Name)
+		assert.Equal(t, root.SpanID, spans[0].ParentID)
+		assert.Equal(t, errFailed, spans[0].Err)
+		assert.Equal(t, "deployment", spans[1].Name)
+		assert.Empty(t, spans[1].ParentID)
+		assert.Equal(t, map[string]string{"app": "app_1"}, spans[1].Attributes)
+		for _, span := range spans {
+			assert.Equal(t, tracer.TraceID(), span.TraceID)
+		}
+	})
+
+	t.Run("should propagate request id and traceparent of the span to cloud calls", func(t *testing.T) {
+		// arrange
+		tracer := NewTracer()
+
+		// act
+		ctx, span := tracer.Start(context.Background(), "deploy")
+
+		// assert
+		assert.Equal(t, tracer.TraceID(), remitly.RequestID(ctx))
+		assert.Equal(t, span.TraceParent(), remitly.TraceParent(ctx))
+		assert.Regexp(t, regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`), span.TraceParent())
+	})
+
+	t.Run("should keep request id of the deployment", func(t *testing.T) {
+		// arrange
+		tracer := NewTracer()
+		id := NewID()
+
+		// act
+		ctx, _ := tracer.Start(remitly.WithRequestID(context.Background(), id), "deployment")
+		ctx, span := tracer.Start(ctx, "deploy")
+
+		// assert
+		assert.Equal(t, id, remitly.RequestID(ctx))
+		assert.NotEqual(t, tracer.TraceID(), id)
+		assert.Equal(t, tracer.TraceID(), span.TraceID)
+	})
+}
@@ -0,0 +1,9 @@
+package verify
+
+import "github.com/pkg/errors"
+
+var (
+	ErrInvalidBodyPattern = errors.New("value of --verify-body flag must be a valid regular expression")
+	ErrInvalidAttempts    = errors.New("value of --verify-attempts flag must be above zero")
+	ErrVerificationFailed = errors.New("verification of the new revision has failed")
+)
@@ -0,0 +1,145 @@
+package verify
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/hook"
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+)
+
+const (
+	// attemptTimeout limits a single probe of the url or run of the command
+	attemptTimeout = 30 * time.Second
+	// maxBody is the size of the response body matched against the pattern
+	maxBody = 1 << 20
+)
+
+// Verifier checks that the new revision serves traffic, before the old instances are removed
+type Verifier struct {
+	target   string
+	status   int
+	body     *regexp.Regexp
+	attempts int
+	interval time.Duration
+	timeout  time.Duration
+	hc       *http.Client
+	// Output receives output of the verification command
+	Output io.Writer
+}
+
+// AddFlags registers verification flags of given command
+func AddFlags(cmd *cobra.Command) {
+	cmd.Flags().String("verify", "", "Http(s) url probed or shell command run against the new revision before the old instances are removed (optional)")
+	cmd.Flags().Int("verify-status", http.StatusOK, "Status code expected from the probed url (optional)")
+	cmd.Flags().String("verify-body", "", "Regular expression the body of the probed url has to match (optional)")
+	cmd.Flags().Int("verify-attempts", 3, "The number of attempts before the verification fails (optional)")
+	cmd.Flags().Duration("verify-interval", 5*time.Second, "Time between attempts of the verification (optional)")
+	cmd.Flags().Duration("verify-timeout", attemptTimeout, "Time limit of a single attempt of the verification (optional)")
+}
+
+// FromFlags returns verifier configured by the flags of given command
+func FromFlags(cmd *cobra.Command) (Verifier, error) {
+	v := Verifier{timeout: attemptTimeout, hc: &http.Client{Timeout: attemptTimeout}, Output: cmd.ErrOrStderr()}
+	v.target, _ = cmd.Flags().GetString("verify")
+	v.status, _ = cmd.Flags().GetInt("verify-status")
+	v.attempts, _ = cmd.Flags().GetInt("verify-attempts")
+	v.interval, _ = cmd.Flags().GetDuration("verify-interval")
+	if timeout, err := cmd.Flags().GetDuration("verify-timeout"); err == nil && timeout > 0 {
+		v.timeout, v.hc.Timeout = timeout, timeout
+	}
+	if v.attempts <= 0 {
+		return Verifier{}, ErrInvalidAttempts
+	}
+	if pattern, _ := cmd.Flags().GetString("verify-body"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return Verifier{}, errors.Wrapf(ErrInvalidBodyPattern, "%v", err)
+		}
+		v.body = re
+	}
+	return v, nil
+}
+
+// Enabled returns true when the verification is requested
+func (v Verifier) Enabled() bool {
+	return v.target != ""
+}
+
+// Verify probes the url or runs the command until it succeeds or attempts are exhausted,
+// environment variables of env are expanded in the url and passed to the command
+func (v Verifier) Verify(ctx context.Context, env hook.Env) error {
+	var err error
+	for attempt := 1; attempt <= v.attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return errors.Wrapf(ErrVerificationFailed, "%v", ctx.Err())
+			case <-time.After(v.interval):
+			}
+		}
+		if err = v.attempt(ctx, env); err == nil {
+			logging.FromContext(ctx).WithField("attempt", attempt).Info("verification succeeded")
+			return nil
+		}
+		logging.FromContext(ctx).WithField("attempt", attempt).WithError(err).Warn("verification attempt failed")
+	}
+	return errors.Wrapf(ErrVerificationFailed, "%v", err)
+}
+
+// attempt probes the url or runs the command once, a hanging attempt fails once its timeout elapses,
+// the command is killed along with its children like hooks are
+func (v Verifier) attempt(ctx context.Context, env hook.Env) error {
+	attemptCtx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+	err := v.probe(attemptCtx, env)
+	if err != nil && ctx.Err() == nil && attemptCtx.Err() == context.DeadlineExceeded {
+		return errors.Errorf("timed out after %s", v.timeout)
+	}
+	return err
+}
+
+func (v Verifier) probe(ctx context.Context, env hook.Env) error {
+	if !isURL(v.target) {
+		return hook.Exec(ctx, v.target, env, v.Output)
+	}
+	u := os.Expand(v.target, func(key string) string {
+		if value, exists := env[key]; exists {
+			return value
+		}
+		return os.Getenv(key)
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	res, err := v.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxBody))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != v.status {
+		return errors.Errorf("'%s' responded with status %d, expected %d", u, res.StatusCode, v.status)
+	}
+	if v.body != nil && !v.body.Match(body) {
+		return errors.Errorf("body of '%s' does not match '%s'", u, v.body)
+	}
+	return nil
+}
+
+func isURL(target string) bool {
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
@@ -0,0 +1,153 @@
+package verify
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/internal/hook"
+)
+
+func newCmd(args ...string) *cobra.Command {
+	cmd := &cobra.Command{Use: "deploy"}
+	AddFlags(cmd)
+	_ = cmd.ParseFlags(append([]string{"--verify-interval", "1ms"}, args...))
+	return cmd
+}
+
+func TestFromFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		giveArgs    []string
+		wantEnabled bool
+		wantErr     error
+	}{
+		{
+			name:        "should be disabled by default",
+			giveArgs:    nil,
+			wantEnabled: false,
+			wantErr:     nil,
+		},
+		{
+			name:        "should be enabled when target specified",
+			giveArgs:    []string{"--verify", "http://app.internal/health"},
+			wantEnabled: true,
+			wantErr:     nil,
+		},
+		{
+			name:        "should return error when body pattern is invalid",
+			giveArgs:    []string{"--verify", "http://app.internal/health", "--verify-body", "("},
+			wantEnabled: false,
+			wantErr:     ErrInvalidBodyPattern,
+		},
+		{
+			name:        "should return error when attempts are not above zero",
+			giveArgs:    []string{"--verify", "true", "--verify-attempts", "0"},
+			wantEnabled: false,
+			wantErr:     ErrInvalidAttempts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			v, err := FromFlags(newCmd(tt.giveArgs...))
+
+			// assert
+			assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+			assert.Equal(t, tt.wantEnabled, v.Enabled())
+		})
+	}
+}
+
+func TestVerifierVerify(t *testing.T) {
+	// the probed app is healthy from the second request on, it reports its revision
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"revision":"` + r.URL.Query().Get("revision") + `"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		giveArgs     []string
+		wantRequests int32
+		wantErr      error
+	}{
+		{
+			name:         "should succeed once probed url responds with expected status and body",
+			giveArgs:     []string{"--verify", srv.URL + "?revision=$REMITLY_REVISION", "--verify-body", `"revision":"2\.0\.0"`},
+			wantRequests: 2,
+			wantErr:      nil,
+		},
+		{
+			name:         "should fail when body does not match",
+			giveArgs:     []string{"--verify", srv.URL + "?revision=1.0.0", "--verify-body", `"revision":"2\.0\.0"`},
+			wantRequests: 3,
+			wantErr:      ErrVerificationFailed,
+		},
+		{
+			name:         "should fail when status is not expected",
+			giveArgs:     []string{"--verify", srv.URL, "--verify-status", "204", "--verify-attempts", "2"},
+			wantRequests: 2,
+			wantErr:      ErrVerificationFailed,
+		},
+		{
+			name:         "should succeed when command exits with zero code",
+			giveArgs:     []string{"--verify", `test "$REMITLY_REVISION" = 2.0.0`},
+			wantRequests: 0,
+			wantErr:      nil,
+		},
+		{
+			name:         "should fail when command exits with non-zero code",
+			giveArgs:     []string{"--verify", "exit 1"},
+			wantRequests: 0,
+			wantErr:      ErrVerificationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			atomic.StoreInt32(&requests, 0)
+			v, err := FromFlags(newCmd(tt.giveArgs...))
+			assert.NoError(t, err)
+			v.Output = ioutil.Discard
+
+			// act
+			err = v.Verify(context.Background(), hook.Env{"REMITLY_REVISION": "2.0.0"})
+
+			// assert
+			assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+			assert.Equal(t, tt.wantRequests, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func TestVerifierVerifyHangingCommand(t *testing.T) {
+	// arrange
+	v, err := FromFlags(newCmd("--verify", "sleep 10", "--verify-attempts", "2", "--verify-timeout", "100ms"))
+	assert.NoError(t, err)
+	v.Output = ioutil.Discard
+
+	// act
+	start := time.Now()
+	err = v.Verify(context.Background(), hook.Env{})
+
+	// assert
+	assert.True(t, errors.Is(err, ErrVerificationFailed), "unexpected error: %v", err)
+	assert.Contains(t, err.Error(), "timed out after 100ms")
+	assert.True(t, time.Since(start) < 5*time.Second, time.Since(start))
+}
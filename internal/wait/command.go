@@ -0,0 +1,131 @@
+package wait
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/deploy"
+	"github.com/mazxaxz/remitly-cli/internal/output"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+const (
+	version = "1.0.0"
+)
+
+type cmdContext struct {
+	app, revision string
+	loadBalancer  string
+	condition     condition
+	replicas      int
+	timeout       time.Duration
+	printer       output.Printer
+}
+
+func NewCmd() *cobra.Command {
+	var c cmdContext
+
+	cmd := cobra.Command{
+		Use:     "wait",
+		Version: version,
+		Short:   "A subcommand blocking until a revision of the application reaches a condition",
+		Long: `
+A subcommand blocking until a revision of the application
+reaches a condition, i.e. a deployment started elsewhere
+has finished:
+	healthy  - at least --replica-count instances of the revision exist, all of them healthy
+	deployed - the revision is healthy and instances of other revisions are gone
+	deleted  - no instance of the revision exists
+
+Subcommand uses:
+	'REMITLY_PROFILE' - environment variable (optional, default: default)
+	'REMITLY_PATH' - created by 'remitly initialize ...' (required)
+`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if err := profile.LoadSettings(cmd, args); err != nil {
+				return err
+			}
+			if !c.condition.valid() {
+				return ErrInvalidCondition
+			}
+			p, err := output.NewPrinter(cmd)
+			if err != nil {
+				return err
+			}
+			c.printer = p
+			return nil
+		},
+		RunE: c.run,
+	}
+
+	cmd.Flags().StringVarP(&c.app, "application", "a", "", "Application name (required)")
+	cmd.MarkFlagRequired("application")
+	cmd.Flags().StringVar(&c.revision, "revision", "", "The version of the application to wait for (required)")
+	cmd.MarkFlagRequired("revision")
+	cmd.Flags().StringVar((*string)(&c.condition), "for", string(conditionHealthy), "Condition to wait for, one of: healthy|deployed|deleted (optional)")
+	cmd.Flags().IntVar(&c.replicas, "replica-count", 1, "The minimal number of instances of the revision (optional)")
+	cmd.Flags().DurationVar(&c.timeout, "timeout", 10*time.Minute, "The time to wait for the condition (optional)")
+	cmd.Flags().StringVar(&c.loadBalancer, "load-balancer", "", "The name of the load balancer (optional, default: derived from --application)")
+
+	return &cmd
+}
+
+func (c *cmdContext) run(cmd *cobra.Command, _ []string) error {
+	pc, err := profile.Current()
+	if err != nil {
+		return err
+	}
+	rc, err := pc.NewClient()
+	if err != nil {
+		return err
+	}
+	lb := c.loadBalancer
+	if lb == "" {
+		if lb, err = pc.LoadBalancer(c.app); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), c.timeout)
+	defer cancel()
+
+	r := result{App: c.app, LoadBalancer: lb, Revision: c.revision, Condition: string(c.condition)}
+	start := time.Now()
+	f := log.Fields{"app": c.app, "revision": c.revision, "condition": c.condition}
+	log.WithContext(ctx).WithFields(f).Info("waiting for condition")
+	err = deploy.Poll(ctx, rc, lb, func(instances []remitly.Instance) (bool, error) {
+		r.Instances = count(instances, c.revision)
+		met, err := c.condition.met(instances, c.revision, c.replicas)
+		r.Met = met
+		return met, err
+	})
+	r.Milliseconds = time.Since(start).Milliseconds()
+	if err != nil {
+		if ctx.Err() != nil && !errors.Is(err, ErrInstancesUnhealthy) {
+			err = errors.Wrapf(ErrConditionNotMet, "'%s' of '%s' after %s", c.condition, c.revision, c.timeout)
+		}
+		if perr := c.printer.Print(r); perr != nil {
+			return perr
+		}
+		return err
+	}
+	log.WithContext(ctx).WithFields(f).Info("condition met")
+	return c.printer.Print(r)
+}
+
+func count(instances []remitly.Instance, revision string) int {
+	n := 0
+	for _, instance := range instances {
+		if instance.Version == revision {
+			n++
+		}
+	}
+	return n
+}
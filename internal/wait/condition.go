@@ -0,0 +1,52 @@
+package wait
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+type condition string
+
+const (
+	// conditionHealthy holds when enough instances of the revision exist and all of them are healthy
+	conditionHealthy = condition("healthy")
+	// conditionDeployed holds when the revision is healthy and instances of other revisions are gone
+	conditionDeployed = condition("deployed")
+	// conditionDeleted holds when no instance of the revision exists
+	conditionDeleted = condition("deleted")
+)
+
+func (c condition) valid() bool {
+	return c == conditionHealthy || c == conditionDeployed || c == conditionDeleted
+}
+
+// met returns true when instances satisfy the condition for the revision with at least replicas instances,
+// ErrInstancesUnhealthy when it cannot be satisfied anymore
+func (c condition) met(instances []remitly.Instance, revision string, replicas int) (bool, error) {
+	var count, healthy, others int
+	for _, instance := range instances {
+		if instance.Version != revision {
+			others++
+			continue
+		}
+		count++
+		switch instance.Status {
+		case remitly.StateHealthy:
+			healthy++
+		case remitly.StateUnhealthy:
+			if c != conditionDeleted {
+				return false, errors.Wrapf(ErrInstancesUnhealthy, "instance '%s' of '%s' is unhealthy", instance.ID, revision)
+			}
+		}
+	}
+
+	switch c {
+	case conditionDeleted:
+		return count == 0, nil
+	case conditionDeployed:
+		return count >= replicas && healthy == count && others == 0, nil
+	default:
+		return count >= replicas && healthy == count, nil
+	}
+}
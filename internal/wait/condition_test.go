@@ -0,0 +1,93 @@
+package wait
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+func TestConditionMet(t *testing.T) {
+	healthy := func(ID, version string) remitly.Instance {
+		return remitly.Instance{ID: ID, Version: version, Status: remitly.StateHealthy}
+	}
+	tests := []struct {
+		name          string
+		giveCondition condition
+		giveInstances []remitly.Instance
+		giveReplicas  int
+		wantMet       bool
+		wantErr       error
+	}{
+		{
+			name:          "should be healthy when all instances of the revision are healthy",
+			giveCondition: conditionHealthy,
+			giveInstances: []remitly.Instance{healthy("ins_1", "1"), healthy("ins_2", "2"), healthy("ins_3", "2")},
+			giveReplicas:  2,
+			wantMet:       true,
+		},
+		{
+			name:          "should not be healthy when there are less instances than replicas",
+			giveCondition: conditionHealthy,
+			giveInstances: []remitly.Instance{healthy("ins_1", "2")},
+			giveReplicas:  2,
+			wantMet:       false,
+		},
+		{
+			name:          "should not be healthy when instance is provisioning",
+			giveCondition: conditionHealthy,
+			giveInstances: []remitly.Instance{healthy("ins_1", "2"), {ID: "ins_2", Version: "2", Status: remitly.StateProvisioning}},
+			giveReplicas:  1,
+			wantMet:       false,
+		},
+		{
+			name:          "should return error when instance of the revision is unhealthy",
+			giveCondition: conditionHealthy,
+			giveInstances: []remitly.Instance{healthy("ins_1", "2"), {ID: "ins_2", Version: "2", Status: remitly.StateUnhealthy}},
+			giveReplicas:  1,
+			wantMet:       false,
+			wantErr:       ErrInstancesUnhealthy,
+		},
+		{
+			name:          "should not be deployed while instances of other revisions exist",
+			giveCondition: conditionDeployed,
+			giveInstances: []remitly.Instance{healthy("ins_1", "1"), healthy("ins_2", "2")},
+			giveReplicas:  1,
+			wantMet:       false,
+		},
+		{
+			name:          "should be deployed when only healthy instances of the revision exist",
+			giveCondition: conditionDeployed,
+			giveInstances: []remitly.Instance{healthy("ins_2", "2")},
+			giveReplicas:  1,
+			wantMet:       true,
+		},
+		{
+			name:          "should be deleted when no instance of the revision exists",
+			giveCondition: conditionDeleted,
+			giveInstances: []remitly.Instance{healthy("ins_1", "1")},
+			giveReplicas:  1,
+			wantMet:       true,
+		},
+		{
+			name:          "should not be deleted while unhealthy instance of the revision exists",
+			giveCondition: conditionDeleted,
+			giveInstances: []remitly.Instance{{ID: "ins_2", Version: "2", Status: remitly.StateUnhealthy}},
+			giveReplicas:  1,
+			wantMet:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			met, err := tt.giveCondition.met(tt.giveInstances, "2", tt.giveReplicas)
+
+			// assert
+			assert.Equal(t, tt.wantMet, met)
+			assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+		})
+	}
+}
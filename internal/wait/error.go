@@ -0,0 +1,9 @@
+package wait
+
+import "github.com/pkg/errors"
+
+var (
+	ErrInvalidCondition   = errors.New("value of --for flag must be one of: healthy, deployed, deleted")
+	ErrConditionNotMet    = errors.New("condition has not been met before the timeout")
+	ErrInstancesUnhealthy = errors.New("instances of the revision are unhealthy")
+)
@@ -0,0 +1,29 @@
+package wait
+
+import (
+	"strconv"
+	"time"
+)
+
+type result struct {
+	App          string `json:"app" yaml:"app"`
+	LoadBalancer string `json:"loadBalancer" yaml:"loadBalancer"`
+	Revision     string `json:"revision" yaml:"revision"`
+	Condition    string `json:"condition" yaml:"condition"`
+	Met          bool   `json:"met" yaml:"met"`
+	Instances    int    `json:"instances" yaml:"instances"`
+	Milliseconds int64  `json:"milliseconds" yaml:"milliseconds"`
+}
+
+func (r result) Table() ([]string, [][]string) {
+	rows := [][]string{
+		{"APP", r.App},
+		{"LOAD BALANCER", r.LoadBalancer},
+		{"REVISION", r.Revision},
+		{"CONDITION", r.Condition},
+		{"MET", strconv.FormatBool(r.Met)},
+		{"INSTANCES", strconv.Itoa(r.Instances)},
+		{"WAITED", (time.Duration(r.Milliseconds) * time.Millisecond).String()},
+	}
+	return nil, rows
+}
@@ -0,0 +1,138 @@
+package watch
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/output"
+	"github.com/mazxaxz/remitly-cli/internal/profile"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+const (
+	version = "1.0.0"
+)
+
+type cmdContext struct {
+	app          string
+	loadBalancer string
+	interval     time.Duration
+	timeout      time.Duration
+	printer      output.Printer
+}
+
+func NewCmd() *cobra.Command {
+	var c cmdContext
+
+	cmd := cobra.Command{
+		Use:     "watch",
+		Version: version,
+		Short:   "A subcommand streaming state changes of the application instances",
+		Long: `
+A subcommand polling instances of the application and printing
+an event whenever an instance appears, changes its status or disappears.
+Instances found by the first poll are reported as 'existing'.
+Use '-o jsonl' for a single line of json per event.
+
+Subcommand uses:
+	'REMITLY_PROFILE' - environment variable (optional, default: default)
+	'REMITLY_PATH' - created by 'remitly initialize ...' (required)
+`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if err := profile.LoadSettings(cmd, args); err != nil {
+				return err
+			}
+			if c.interval <= 0 {
+				return ErrInvalidInterval
+			}
+			p, err := output.NewPrinter(cmd)
+			if err != nil {
+				return err
+			}
+			c.printer = p
+			return nil
+		},
+		RunE: c.run,
+	}
+
+	cmd.Flags().StringVarP(&c.app, "application", "a", "", "Application name (required)")
+	cmd.MarkFlagRequired("application")
+	cmd.Flags().StringVar(&c.loadBalancer, "load-balancer", "", "The name of the load balancer (optional, default: derived from --application)")
+	cmd.Flags().DurationVar(&c.interval, "interval", 2*time.Second, "The interval between polls (optional)")
+	cmd.Flags().DurationVar(&c.timeout, "timeout", 0, "Stops watching after given time (optional, default: until interrupted)")
+
+	return &cmd
+}
+
+func (c *cmdContext) run(cmd *cobra.Command, _ []string) error {
+	pc, err := profile.Current()
+	if err != nil {
+		return err
+	}
+	rc, err := pc.NewClient()
+	if err != nil {
+		return err
+	}
+	lb := c.loadBalancer
+	if lb == "" {
+		if lb, err = pc.LoadBalancer(c.app); err != nil {
+			return err
+		}
+	}
+
+	ctx := cmd.Context()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	log.WithContext(ctx).WithFields(log.Fields{"app": c.app, "loadBalancer": lb}).Info("watching instances")
+	err = c.watch(ctx, rc, lb)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// watch prints events until the context is done. Failed polls are logged and retried,
+// so the watch survives a flapping cloud, except for rejected credentials
+func (c *cmdContext) watch(ctx context.Context, rc remitly.Clienter, lb string) error {
+	var previous []remitly.Instance
+	first := true
+	for {
+		instances, err := rc.GetInstances(ctx, lb)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.Is(err, remitly.ErrForbidden):
+			return err
+		case err != nil && !errors.Is(err, remitly.ErrNotFound):
+			log.WithContext(ctx).WithError(err).WithField("loadBalancer", lb).Warn("could not get instances")
+		default:
+			now := time.Now().UTC()
+			for _, e := range diff(previous, instances) {
+				if first {
+					e.Type = EventExisting
+				}
+				e.Time, e.LoadBalancer = now, lb
+				if err := c.printer.PrintStream(e); err != nil {
+					return err
+				}
+			}
+			previous, first = instances, false
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.interval):
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package watch
+
+import "github.com/pkg/errors"
+
+var (
+	ErrInvalidInterval = errors.New("value of --interval flag must be positive")
+)
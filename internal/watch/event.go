@@ -0,0 +1,74 @@
+package watch
+
+import (
+	"time"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+// EventType describes what has happened to an instance between two polls
+type EventType string
+
+const (
+	// EventExisting is reported for instances found by the first poll
+	EventExisting = EventType("existing")
+	// EventAppeared is reported for instances created since the previous poll
+	EventAppeared = EventType("appeared")
+	// EventChanged is reported for instances whose status has changed since the previous poll
+	EventChanged = EventType("changed")
+	// EventDisappeared is reported for instances deleted since the previous poll
+	EventDisappeared = EventType("disappeared")
+)
+
+// Event is a single change of the instances behind the load balancer
+type Event struct {
+	Time           time.Time     `json:"time" yaml:"time"`
+	Type           EventType     `json:"type" yaml:"type"`
+	LoadBalancer   string        `json:"loadBalancer" yaml:"loadBalancer"`
+	ID             string        `json:"id" yaml:"id"`
+	Version        string        `json:"version" yaml:"version"`
+	Status         remitly.State `json:"status" yaml:"status"`
+	PreviousStatus remitly.State `json:"previousStatus,omitempty" yaml:"previousStatus,omitempty"`
+}
+
+func (e Event) Table() ([]string, [][]string) {
+	status := string(e.Status)
+	if e.PreviousStatus != "" {
+		status = string(e.PreviousStatus) + " -> " + status
+	}
+	return nil, [][]string{{e.Time.Format(time.RFC3339), string(e.Type), e.ID, e.Version, status}}
+}
+
+// diff returns events turning previous instances into current ones, in order of current instances
+// followed by the disappeared ones in their previous order
+func diff(previous, current []remitly.Instance) []Event {
+	seen := make(map[string]remitly.Instance, len(previous))
+	for _, instance := range previous {
+		seen[instance.ID] = instance
+	}
+
+	var events []Event
+	present := make(map[string]bool, len(current))
+	for _, instance := range current {
+		present[instance.ID] = true
+		before, ok := seen[instance.ID]
+		switch {
+		case !ok:
+			events = append(events, event(EventAppeared, instance))
+		case before.Status != instance.Status:
+			e := event(EventChanged, instance)
+			e.PreviousStatus = before.Status
+			events = append(events, e)
+		}
+	}
+	for _, instance := range previous {
+		if !present[instance.ID] {
+			events = append(events, event(EventDisappeared, instance))
+		}
+	}
+	return events
+}
+
+func event(t EventType, instance remitly.Instance) Event {
+	return Event{Type: t, ID: instance.ID, Version: instance.Version, Status: instance.Status}
+}
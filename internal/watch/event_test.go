@@ -0,0 +1,133 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/internal/output"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+func TestDiff(t *testing.T) {
+	instance := func(ID string, status remitly.State) remitly.Instance {
+		return remitly.Instance{ID: ID, Version: "1", Status: status}
+	}
+	tests := []struct {
+		name         string
+		givePrevious []remitly.Instance
+		giveCurrent  []remitly.Instance
+		wantEvents   []Event
+	}{
+		{
+			name:         "should not report anything when instances are unchanged",
+			givePrevious: []remitly.Instance{instance("ins_1", remitly.StateHealthy)},
+			giveCurrent:  []remitly.Instance{instance("ins_1", remitly.StateHealthy)},
+		},
+		{
+			name:         "should report appeared instances",
+			givePrevious: []remitly.Instance{instance("ins_1", remitly.StateHealthy)},
+			giveCurrent:  []remitly.Instance{instance("ins_1", remitly.StateHealthy), instance("ins_2", remitly.StateProvisioning)},
+			wantEvents:   []Event{{Type: EventAppeared, ID: "ins_2", Version: "1", Status: remitly.StateProvisioning}},
+		},
+		{
+			name:         "should report status changes",
+			givePrevious: []remitly.Instance{instance("ins_1", remitly.StateProvisioning)},
+			giveCurrent:  []remitly.Instance{instance("ins_1", remitly.StateHealthy)},
+			wantEvents: []Event{
+				{Type: EventChanged, ID: "ins_1", Version: "1", Status: remitly.StateHealthy, PreviousStatus: remitly.StateProvisioning},
+			},
+		},
+		{
+			name:         "should report disappeared instances after the current ones",
+			givePrevious: []remitly.Instance{instance("ins_1", remitly.StateHealthy), instance("ins_2", remitly.StateHealthy)},
+			giveCurrent:  []remitly.Instance{instance("ins_3", remitly.StateProvisioning)},
+			wantEvents: []Event{
+				{Type: EventAppeared, ID: "ins_3", Version: "1", Status: remitly.StateProvisioning},
+				{Type: EventDisappeared, ID: "ins_1", Version: "1", Status: remitly.StateHealthy},
+				{Type: EventDisappeared, ID: "ins_2", Version: "1", Status: remitly.StateHealthy},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			events := diff(tt.givePrevious, tt.giveCurrent)
+
+			// assert
+			assert.Equal(t, tt.wantEvents, events)
+		})
+	}
+}
+
+// scripted returns the next list of instances on every call and cancels the watch after the last one
+type scripted struct {
+	remitly.Clienter
+	polls  [][]remitly.Instance
+	errs   []error
+	cancel context.CancelFunc
+}
+
+func (s *scripted) GetInstances(context.Context, string) ([]remitly.Instance, error) {
+	instances, err := s.polls[0], s.errs[0]
+	s.polls, s.errs = s.polls[1:], s.errs[1:]
+	if len(s.polls) == 0 {
+		s.cancel()
+	}
+	return instances, err
+}
+
+func TestWatch(t *testing.T) {
+	t.Run("should print events of consecutive polls skipping failed ones", func(t *testing.T) {
+		// arrange
+		var out bytes.Buffer
+		cmd := &cobra.Command{Use: "test"}
+		output.AddFlags(cmd)
+		cmd.SetOut(&out)
+		assert.NoError(t, cmd.ParseFlags([]string{"-o", "template", "--template", "{{ .Type }} {{ .ID }} {{ .Status }}"}))
+		printer, err := output.NewPrinter(cmd)
+		assert.NoError(t, err)
+		c := cmdContext{interval: time.Millisecond, printer: printer}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		rc := &scripted{
+			polls: [][]remitly.Instance{
+				{{ID: "ins_1", Status: remitly.StateHealthy}},
+				{{ID: "ins_1", Status: remitly.StateHealthy}, {ID: "ins_2", Status: remitly.StateProvisioning}},
+				nil,
+				{{ID: "ins_2", Status: remitly.StateHealthy}},
+				nil,
+			},
+			errs:   []error{nil, nil, remitly.ErrUnknown, nil, remitly.ErrNotFound},
+			cancel: cancel,
+		}
+
+		// act
+		err = c.watch(ctx, rc, "lb_1")
+
+		// assert
+		assert.Equal(t, context.Canceled, err)
+		assert.Equal(t, "existing ins_1 healthy\n"+
+			"appeared ins_2 provisioning\n"+
+			"changed ins_2 healthy\n"+
+			"disappeared ins_1 healthy\n", out.String())
+	})
+
+	t.Run("should stop when credentials are rejected", func(t *testing.T) {
+		// arrange
+		c := cmdContext{interval: time.Millisecond}
+		rc := &scripted{polls: [][]remitly.Instance{nil, nil}, errs: []error{remitly.ErrForbidden, nil}}
+
+		// act
+		err := c.watch(context.Background(), rc, "lb_1")
+
+		// assert
+		assert.Equal(t, remitly.ErrForbidden, err)
+	})
+}
@@ -0,0 +1,306 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/mazxaxz/remitly-cli/internal/logging"
+)
+
+const (
+	// SignatureHeader carries 'sha256=' prefixed hex HMAC of the body, signed by the secret of the target
+	SignatureHeader = "X-Remitly-Signature"
+	// EventHeader carries type of the event
+	EventHeader = "X-Remitly-Event"
+
+	webhookFlag        = "webhook"
+	webhookTimeoutFlag = "webhook-timeout"
+	// DefaultTimeout is the longest time Close waits for queued events to be posted
+	DefaultTimeout = 10 * time.Second
+	// queueSize bounds events waiting for delivery, a deployment posts far fewer of them
+	queueSize = 64
+	// secretEnv signs events sent to the targets specified by flags, secrets are not passed as flags
+	// so they do not leak into shell history and process lists
+	secretEnv = "REMITLY_WEBHOOK_SECRET"
+)
+
+// Types of events
+const (
+	DeploymentStarted   = "deployment.started"
+	DeploymentStep      = "deployment.step"
+	DeploymentSucceeded = "deployment.succeeded"
+	DeploymentFailed    = "deployment.failed"
+	RollbackStarted     = "rollback.started"
+	RollbackSucceeded   = "rollback.succeeded"
+	RollbackFailed      = "rollback.failed"
+)
+
+// Event describes a single moment of a deployment
+type Event struct {
+	Type         string    `json:"type"`
+	DeploymentID string    `json:"deploymentId"`
+	Context      string    `json:"context"`
+	App          string    `json:"app"`
+	Revision     string    `json:"revision"`
+	LoadBalancer string    `json:"loadBalancer"`
+	Step         string    `json:"step,omitempty"`
+	Code         string    `json:"code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+	// Text is a human readable description of the event, rendered as is by chat incoming webhooks
+	Text string `json:"text"`
+}
+
+// Target is an endpoint events are posted to
+type Target struct {
+	URL string
+	// Secret signs events when not empty
+	Secret string
+	// Events filters types of posted events, all events are posted when empty
+	Events []string
+}
+
+func (t Target) accepts(eventType string) bool {
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, e := range t.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifier posts events to its targets in the background, in order of notifications, until it is closed
+type Notifier struct {
+	targets  []Target
+	hc       *http.Client
+	attempts int
+	backoff  time.Duration
+	// timeout is the longest time Close waits for queued events to be posted
+	timeout time.Duration
+	queue   chan delivery
+	start   sync.Once
+	close   sync.Once
+	// expired is done once the timeout of Close has passed, posts in progress are cancelled
+	// and queued events are dropped
+	expired context.Context
+	expire  context.CancelFunc
+	// delivered is closed once every queued event has been either posted or dropped
+	delivered chan struct{}
+	// dropped holds types of events which have not been posted to all their targets, guarded by delivered
+	dropped []string
+}
+
+// delivery is an event waiting in the queue
+type delivery struct {
+	ctx       context.Context
+	eventType string
+	body      []byte
+}
+
+// AddFlags registers webhook flags of given command
+func AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray(webhookFlag, nil, "Post deployment events to given url, can be repeated, "+
+		"events are signed with $"+secretEnv+" when set (optional)")
+	cmd.Flags().Duration(webhookTimeoutFlag, DefaultTimeout, "The time to wait for events to be posted once the deployment has finished, "+
+		"events which have not been posted by then are dropped (optional)")
+}
+
+// New returns Notifier posting events to given targets and to the ones specified by the flags of given command
+func New(cmd *cobra.Command, targets ...Target) *Notifier {
+	n := Notifier{
+		targets:   targets,
+		hc:        &http.Client{Timeout: 5 * time.Second},
+		attempts:  3,
+		backoff:   500 * time.Millisecond,
+		timeout:   DefaultTimeout,
+		queue:     make(chan delivery, queueSize),
+		delivered: make(chan struct{}),
+	}
+	n.expired, n.expire = context.WithCancel(context.Background())
+	if cmd != nil {
+		urls, _ := cmd.Flags().GetStringArray(webhookFlag)
+		for _, u := range urls {
+			n.targets = append(n.targets, Target{URL: u, Secret: os.Getenv(secretEnv)})
+		}
+		if f := cmd.Flag(webhookTimeoutFlag); f != nil && f.Changed {
+			n.timeout, _ = cmd.Flags().GetDuration(webhookTimeoutFlag)
+		}
+	}
+	return &n
+}
+
+// Notify queues the event to be posted to all targets accepting it and returns immediately,
+// so notifications never slow down the deployment. Failures are logged only, events are dropped
+// when the queue is full. Notify must not be called once the notifier is closed. Events are posted even when the context is done to announce failures
+// caused by timeouts, the context is used for logging only
+func (n *Notifier) Notify(ctx context.Context, e Event) {
+	if n == nil || len(n.targets) == 0 {
+		return
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	if e.Text == "" {
+		e.Text = text(e)
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Warn("could not encode webhook event")
+		return
+	}
+	n.start.Do(func() { go n.deliver() })
+	select {
+	case n.queue <- delivery{ctx: ctx, eventType: e.Type, body: body}:
+	default:
+		logging.FromContext(ctx).WithField("event", e.Type).Warn("webhook queue is full, dropping event")
+	}
+}
+
+// Close waits until queued events have been posted and stops the notifier, at most for its timeout.
+// Events which have not been posted by then are dropped and reported, the context is used for logging only
+func (n *Notifier) Close(ctx context.Context) {
+	if n == nil || len(n.targets) == 0 {
+		return
+	}
+	n.close.Do(func() {
+		n.start.Do(func() { go n.deliver() })
+		close(n.queue)
+		timer := time.NewTimer(n.timeout)
+		defer timer.Stop()
+
+		select {
+		case <-n.delivered:
+		case <-timer.C:
+			n.expire()
+			<-n.delivered
+		}
+		n.expire()
+		if len(n.dropped) > 0 {
+			f := log.Fields{"timeout": n.timeout, "dropped": len(n.dropped), "events": n.dropped}
+			logging.FromContext(ctx).WithFields(f).Warn("could not post all webhook events in time, dropping them")
+		}
+	})
+}
+
+// deliver posts queued events one after another, until the queue is closed
+func (n *Notifier) deliver() {
+	defer close(n.delivered)
+	for d := range n.queue {
+		if n.expired.Err() != nil {
+			n.dropped = append(n.dropped, d.eventType)
+			continue
+		}
+		posted := true
+		for _, t := range n.targets {
+			if !t.accepts(d.eventType) {
+				continue
+			}
+			if err := n.post(t, d.eventType, d.body); err != nil {
+				if n.expired.Err() != nil {
+					posted = false
+					continue
+				}
+				f := log.Fields{"url": t.URL, "event": d.eventType}
+				logging.FromContext(d.ctx).WithFields(f).WithError(err).Warn("could not post webhook event")
+			}
+		}
+		if !posted {
+			n.dropped = append(n.dropped, d.eventType)
+		}
+	}
+}
+
+// post sends the body to the target, retrying on network errors, 429 and 5xx responses with exponential backoff
+func (n *Notifier) post(t Target, eventType string, body []byte) error {
+	var err error
+	backoff := n.backoff
+	for attempt := 1; attempt <= n.attempts; attempt++ {
+		var retry bool
+		if retry, err = n.send(t, eventType, body); err == nil || !retry {
+			return err
+		}
+		if attempt < n.attempts {
+			select {
+			case <-time.After(backoff):
+			case <-n.expired.Done():
+				return n.expired.Err()
+			}
+			backoff *= 2
+		}
+	}
+	return errors.Wrapf(err, "giving up after %d attempts", n.attempts)
+}
+
+func (n *Notifier) send(t Target, eventType string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(n.expired, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	if t.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(t.Secret, body))
+	}
+
+	res, err := n.hc.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_ = res.Body.Close()
+	switch {
+	case res.StatusCode/100 == 2:
+		return false, nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return true, errors.Errorf("http status code: '%d'", res.StatusCode)
+	default:
+		return false, errors.Errorf("http status code: '%d'", res.StatusCode)
+	}
+}
+
+// Sign returns value of the signature header of given body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func text(e Event) string {
+	subject := fmt.Sprintf("%s %s (%s)", e.App, e.Revision, e.Context)
+	switch e.Type {
+	case DeploymentStarted:
+		return fmt.Sprintf("Deploying %s", subject)
+	case DeploymentStep:
+		if e.Error != "" {
+			return fmt.Sprintf("Step '%s' of deploying %s failed: %s", e.Step, subject, e.Error)
+		}
+		return fmt.Sprintf("Step '%s' of deploying %s finished", e.Step, subject)
+	case DeploymentSucceeded:
+		return fmt.Sprintf("Deployed %s", subject)
+	case DeploymentFailed:
+		return fmt.Sprintf("Deploying %s failed with code '%s'", subject, e.Code)
+	case RollbackStarted:
+		return fmt.Sprintf("Rolling back %s", subject)
+	case RollbackSucceeded:
+		return fmt.Sprintf("Rolled back %s", subject)
+	case RollbackFailed:
+		return fmt.Sprintf("Rolling back %s failed, manual intervention is needed: %s", subject, e.Error)
+	default:
+		return subject
+	}
+}
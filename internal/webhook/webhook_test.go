@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receiver records events posted to it, responding with given status codes in order, 200 afterwards
+type receiver struct {
+	mu         sync.Mutex
+	events     []Event
+	signatures []string
+	bodies     [][]byte
+	statuses   []int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := ioutil.ReadAll(req.Body)
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+	var e Event
+	_ = json.Unmarshal(body, &e)
+	r.events = append(r.events, e)
+	r.signatures = append(r.signatures, req.Header.Get(SignatureHeader))
+	r.bodies = append(r.bodies, body)
+}
+
+func newNotifier(targets ...Target) *Notifier {
+	n := New(nil, targets...)
+	n.backoff = time.Millisecond
+	return n
+}
+
+func TestNotifier(t *testing.T) {
+	t.Run("should post signed event", func(t *testing.T) {
+		// arrange
+		r := &receiver{}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+		n := newNotifier(Target{URL: srv.URL, Secret: "secret"})
+
+		// act
+		n.Notify(context.Background(), Event{Type: DeploymentStarted, App: "app", Revision: "1.0.0", Context: "production"})
+		n.Close(context.Background())
+
+		// assert
+		assert.Len(t, r.events, 1)
+		assert.Equal(t, DeploymentStarted, r.events[0].Type)
+		assert.Equal(t, "Deploying app 1.0.0 (production)", r.events[0].Text)
+		assert.False(t, r.events[0].Timestamp.IsZero())
+		assert.Equal(t, Sign("secret", r.bodies[0]), r.signatures[0])
+	})
+
+	t.Run("should not sign event when target has no secret", func(t *testing.T) {
+		// arrange
+		r := &receiver{}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+		n := newNotifier(Target{URL: srv.URL})
+
+		// act
+		n.Notify(context.Background(), Event{Type: DeploymentSucceeded})
+		n.Close(context.Background())
+
+		// assert
+		assert.Equal(t, []string{""}, r.signatures)
+	})
+
+	t.Run("should retry server errors", func(t *testing.T) {
+		// arrange
+		r := &receiver{statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+		n := newNotifier(Target{URL: srv.URL})
+
+		// act
+		n.Notify(context.Background(), Event{Type: DeploymentFailed})
+		n.Close(context.Background())
+
+		// assert
+		assert.Len(t, r.events, 1)
+		assert.Empty(t, r.statuses)
+	})
+
+	t.Run("should give up after all attempts", func(t *testing.T) {
+		// arrange
+		r := &receiver{statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK}}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+		n := newNotifier(Target{URL: srv.URL})
+
+		// act
+		n.Notify(context.Background(), Event{Type: DeploymentFailed})
+		n.Close(context.Background())
+
+		// assert
+		assert.Empty(t, r.events)
+		assert.Equal(t, []int{http.StatusOK}, r.statuses)
+	})
+
+	t.Run("should not retry client errors", func(t *testing.T) {
+		// arrange
+		r := &receiver{statuses: []int{http.StatusBadRequest, http.StatusOK}}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+		n := newNotifier(Target{URL: srv.URL})
+
+		// act
+		n.Notify(context.Background(), Event{Type: DeploymentFailed})
+		n.Close(context.Background())
+
+		// assert
+		assert.Empty(t, r.events)
+		assert.Equal(t, []int{http.StatusOK}, r.statuses)
+	})
+
+	t.Run("should post only events accepted by the target", func(t *testing.T) {
+		// arrange
+		r := &receiver{}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+		n := newNotifier(Target{URL: srv.URL, Events: []string{DeploymentSucceeded, DeploymentFailed}})
+
+		// act
+		n.Notify(context.Background(), Event{Type: DeploymentStarted})
+		n.Notify(context.Background(), Event{Type: DeploymentStep, Step: "snapshot"})
+		n.Notify(context.Background(), Event{Type: DeploymentSucceeded})
+		n.Close(context.Background())
+
+		// assert
+		assert.Len(t, r.events, 1)
+		assert.Equal(t, DeploymentSucceeded, r.events[0].Type)
+	})
+
+	t.Run("should post events even when context is done", func(t *testing.T) {
+		// arrange
+		r := &receiver{}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+		n := newNotifier(Target{URL: srv.URL})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// act
+		n.Notify(ctx, Event{Type: RollbackStarted})
+		n.Close(ctx)
+
+		// assert
+		assert.Len(t, r.events, 1)
+	})
+
+	t.Run("should post events in order", func(t *testing.T) {
+		// arrange
+		r := &receiver{}
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+		n := newNotifier(Target{URL: srv.URL})
+
+		// act
+		n.Notify(context.Background(), Event{Type: RollbackStarted})
+		n.Notify(context.Background(), Event{Type: RollbackSucceeded})
+		n.Notify(context.Background(), Event{Type: DeploymentFailed})
+		n.Close(context.Background())
+
+		// assert
+		types := make([]string, 0, len(r.events))
+		for _, e := range r.events {
+			types = append(types, e.Type)
+		}
+		assert.Equal(t, []string{RollbackStarted, RollbackSucceeded, DeploymentFailed}, types)
+	})
+
+	t.Run("should post queued events before closing", func(t *testing.T) {
+		// arrange
+		r := &receiver{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+			r.ServeHTTP(w, req)
+		}))
+		defer srv.Close()
+		n := newNotifier(Target{URL: srv.URL})
+
+		// act
+		for _, e := range []string{DeploymentStarted, DeploymentStep, RollbackStarted, RollbackSucceeded, DeploymentFailed} {
+			n.Notify(context.Background(), Event{Type: e})
+		}
+		n.Close(context.Background())
+
+		// assert
+		assert.Len(t, r.events, 5)
+		assert.Empty(t, n.dropped)
+	})
+
+	t.Run("should not block when target does not respond", func(t *testing.T) {
+		// arrange
+		block := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			<-block
+		}))
+		defer srv.Close()
+		defer close(block)
+		n := newNotifier(Target{URL: srv.URL})
+		n.timeout = 100 * time.Millisecond
+
+		// act
+		start := time.Now()
+		for _, e := range []string{DeploymentStarted, DeploymentStep, RollbackStarted, RollbackSucceeded, DeploymentFailed} {
+			n.Notify(context.Background(), Event{Type: e})
+		}
+		notified := time.Since(start)
+		n.Close(context.Background())
+		closed := time.Since(start)
+
+		// assert
+		assert.True(t, notified < 50*time.Millisecond, notified)
+		assert.True(t, closed < time.Second, closed)
+		assert.Equal(t, []string{DeploymentStarted, DeploymentStep, RollbackStarted, RollbackSucceeded, DeploymentFailed}, n.dropped)
+	})
+}
@@ -0,0 +1,246 @@
+package remitly
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// RecordEnv is the environment variable pointing at the directory cassettes are recorded into
+	RecordEnv = "REMITLY_RECORD"
+	// ReplayEnv is the environment variable pointing at the directory cassettes are replayed from
+	ReplayEnv = "REMITLY_REPLAY"
+
+	scrubbed = "REDACTED"
+)
+
+var (
+	ErrInteractionNotRecorded = errors.New("no recorded interaction matches the request")
+
+	// sensitiveHeaders are never written into cassettes
+	sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+)
+
+// cassette is a single recorded request and response pair
+type cassette struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// WithRecording records every interaction with the cloud as a cassette inside given directory,
+// calls are made through the transport configured by the other options regardless of their order
+func WithRecording(dir string) Option {
+	return func(c *clientContext) {
+		c.wrap = func(next http.RoundTripper) http.RoundTripper {
+			return &recorder{dir: dir, next: next}
+		}
+	}
+}
+
+// WithReplay replays interactions recorded inside given directory instead of calling the cloud
+func WithReplay(dir string) Option {
+	return func(c *clientContext) {
+		c.wrap = func(http.RoundTripper) http.RoundTripper {
+			return &replayer{dir: dir}
+		}
+	}
+}
+
+// CassettesFromEnv returns options recording or replaying cassettes
+// when 'REMITLY_RECORD' or 'REMITLY_REPLAY' environment variables are set
+func CassettesFromEnv() []Option {
+	if dir := os.Getenv(ReplayEnv); dir != "" {
+		return []Option{WithReplay(dir)}
+	}
+	if dir := os.Getenv(RecordEnv); dir != "" {
+		return []Option{WithRecording(dir)}
+	}
+	return nil
+}
+
+type recorder struct {
+	mu   sync.Mutex
+	dir  string
+	next http.RoundTripper
+	seq  int
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := drain(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := drain(&res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c := cassette{
+		Request: recordedRequest{
+			Method: req.Method,
+			Path:   req.URL.RequestURI(),
+			Header: scrub(req.Header),
+			Body:   reqBody,
+		},
+		Response: recordedResponse{
+			StatusCode: res.StatusCode,
+			Header:     scrub(res.Header),
+			Body:       resBody,
+		},
+	}
+	if err := r.save(c); err != nil {
+		_ = res.Body.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *recorder) save(c cassette) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.seq == 0 {
+		if err := os.MkdirAll(r.dir, os.FileMode(0755)); err != nil {
+			return errors.Wrapf(err, "could not create directory: '%s'", r.dir)
+		}
+		// continue numbering, so consecutive runs can record into the same directory
+		files, err := cassetteFiles(r.dir)
+		if err != nil {
+			return err
+		}
+		r.seq = len(files)
+	}
+	r.seq++
+
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(r.dir, fmt.Sprintf("%06d.json", r.seq))
+	if err := ioutil.WriteFile(file, b, os.FileMode(0644)); err != nil {
+		return errors.Wrapf(err, "could not write into file: '%s'", file)
+	}
+	return nil
+}
+
+type replayer struct {
+	once      sync.Once
+	loadErr   error
+	mu        sync.Mutex
+	dir       string
+	cassettes []cassette
+	used      []bool
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.once.Do(r.load)
+	if r.loadErr != nil {
+		return nil, r.loadErr
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, c := range r.cassettes {
+		if r.used[i] || c.Request.Method != req.Method || c.Request.Path != req.URL.RequestURI() {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", c.Response.StatusCode, http.StatusText(c.Response.StatusCode)),
+			StatusCode:    c.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        c.Response.Header,
+			Body:          ioutil.NopCloser(strings.NewReader(c.Response.Body)),
+			ContentLength: int64(len(c.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, errors.Wrapf(ErrInteractionNotRecorded, "%s %s", req.Method, req.URL.RequestURI())
+}
+
+// load reads cassettes in order of recording
+func (r *replayer) load() {
+	files, err := cassetteFiles(r.dir)
+	if err != nil {
+		r.loadErr = err
+		return
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			r.loadErr = errors.Wrapf(err, "could not read file: '%s'", file)
+			return
+		}
+		var c cassette
+		if err := json.Unmarshal(b, &c); err != nil {
+			r.loadErr = errors.Wrapf(err, "could not parse cassette: '%s'", file)
+			return
+		}
+		r.cassettes = append(r.cassettes, c)
+	}
+	r.used = make([]bool, len(r.cassettes))
+}
+
+func cassetteFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// drain reads and closes the body and replaces it with an unread copy
+func drain(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+	original := *body
+	defer func() { _ = original.Close() }()
+	b, err := ioutil.ReadAll(original)
+	if err != nil {
+		return "", err
+	}
+	*body = ioutil.NopCloser(bytes.NewReader(b))
+	return string(b), nil
+}
+
+func scrub(src http.Header) http.Header {
+	dst := src.Clone()
+	for _, key := range sensitiveHeaders {
+		if dst.Get(key) != "" {
+			dst.Set(key, scrubbed)
+		}
+	}
+	return dst
+}
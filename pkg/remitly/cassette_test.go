@@ -0,0 +1,122 @@
+package remitly_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly/remitlytest"
+)
+
+func TestCassettes(t *testing.T) {
+	t.Run("should replay recorded interactions without the cloud", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		srv := remitlytest.NewServer()
+		u, _ := url.Parse(srv.URL)
+		ctx := context.Background()
+
+		recording := remitly.NewClient(u, "secret-user", remitly.WithRecording(dir))
+		_, err := recording.CreateLoadBalancer(ctx, "lb_1")
+		assert.NoError(t, err)
+		created, err := recording.CreateInstance(ctx, "lb_1", "1.0.0")
+		assert.NoError(t, err)
+		recorded, err := recording.GetInstances(ctx, "lb_1")
+		assert.NoError(t, err)
+		srv.Close()
+
+		// act
+		replaying := remitly.NewClient(u, "secret-user", remitly.WithReplay(dir))
+		_, errLB := replaying.CreateLoadBalancer(ctx, "lb_1")
+		replayedInstance, errCreate := replaying.CreateInstance(ctx, "lb_1", "1.0.0")
+		replayed, errGet := replaying.GetInstances(ctx, "lb_1")
+
+		// assert
+		assert.NoError(t, errLB)
+		assert.NoError(t, errCreate)
+		assert.NoError(t, errGet)
+		assert.Equal(t, created, replayedInstance)
+		assert.Equal(t, recorded, replayed)
+	})
+
+	t.Run("should scrub auth headers from cassettes", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+		u, _ := url.Parse(srv.URL)
+		rc := remitly.NewClient(u, "secret-user", remitly.WithRecording(dir))
+
+		// act
+		_, err := rc.ListLoadBalancers(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		assert.Len(t, files, 1)
+		for _, file := range files {
+			b, _ := ioutil.ReadFile(file)
+			assert.NotContains(t, string(b), "secret-user")
+			assert.Contains(t, string(b), "REDACTED")
+		}
+	})
+
+	t.Run("should record calls made through proxy regardless of the order of options", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		var host string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host = r.URL.Host
+			_, _ = w.Write([]byte("[]"))
+		}))
+		defer proxy.Close()
+		proxyURL, _ := url.Parse(proxy.URL)
+		u, _ := url.Parse("http://cloud.remitly.io/")
+		rc := remitly.NewClient(u, "user", remitly.WithRecording(dir), remitly.WithProxy(proxyURL))
+
+		// act
+		_, err := rc.ListLoadBalancers(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "cloud.remitly.io", host)
+		files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		assert.Len(t, files, 1)
+	})
+
+	t.Run("should fail when cassette cannot be saved", func(t *testing.T) {
+		// arrange
+		file := filepath.Join(t.TempDir(), "cassettes")
+		assert.NoError(t, ioutil.WriteFile(file, nil, 0644))
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+		u, _ := url.Parse(srv.URL)
+		rc := remitly.NewClient(u, "user", remitly.WithRecording(file))
+
+		// act
+		_, err := rc.ListLoadBalancers(context.Background())
+
+		// assert
+		assert.Error(t, err)
+	})
+
+	t.Run("should fail when interaction was not recorded", func(t *testing.T) {
+		// arrange
+		u, _ := url.Parse("http://127.0.0.1:1/")
+		rc := remitly.NewClient(u, "user", remitly.WithReplay(t.TempDir()))
+
+		// act
+		_, err := rc.ListLoadBalancers(context.Background())
+
+		// assert
+		assert.True(t, errors.Is(err, remitly.ErrInteractionNotRecorded), err)
+	})
+}
@@ -3,6 +3,7 @@ package remitly
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -17,15 +18,30 @@ import (
 type resourceURI string
 
 const (
+	getLoadBalancers             = resourceURI("/loadbalancers")
+	getLoadBalancer              = resourceURI("/loadbalancers/%s")
 	putLoadBalancers             = resourceURI("/loadbalancers/%s")
+	deleteLoadBalancers          = resourceURI("/loadbalancers/%s")
 	getLoadBalancersInstances    = resourceURI("/loadbalancers/%s/instances")
 	postLoadBalancersInstances   = resourceURI("/loadbalancers/%s/instances")
 	deleteLoadBalancersInstances = resourceURI("/loadbalancers/%s/instances/%s")
 )
 
+// endpoint returns the path of the resource with placeholders of load balancer and instance names
+func (r resourceURI) endpoint() string {
+	placeholders := []interface{}{"{name}", "{id}"}
+	return fmt.Sprintf(string(r), placeholders[:strings.Count(string(r), "%s")]...)
+}
+
 type Clienter interface {
+	// ListLoadBalancers returns array of all load balancers
+	ListLoadBalancers(ctx context.Context) ([]LoadBalancer, error)
+	// GetLoadBalancer returns load balancer by name
+	GetLoadBalancer(ctx context.Context, name string) (LoadBalancer, error)
 	// CreateLoadBalancer creates load balancer with given name
 	CreateLoadBalancer(ctx context.Context, name string) (LoadBalancer, error)
+	// DeleteLoadBalancer deletes load balancer by name
+	DeleteLoadBalancer(ctx context.Context, name string) error
 	// GetInstances returns array of instances by load balancer name
 	GetInstances(ctx context.Context, lbName string) ([]Instance, error)
 	// CreateInstance within load balancer scope
@@ -34,29 +50,167 @@ type Clienter interface {
 	DeleteInstance(ctx context.Context, lbName, ID string) error
 }
 
+// DefaultUserAgent identifies calls of clients without a custom User-Agent
+const DefaultUserAgent = "remitly-cli"
+
 type clientContext struct {
 	scheme, hostname string
 	username         string
+	userAgent        string
+	headers          http.Header
+	observers        []func(Call)
+	logger           *log.Logger
 	hc               http.Client
+	// transport is configured by the options and wrapped by wrap once all of them are applied,
+	// so the order of options does not matter
+	transport *http.Transport
+	wrap      func(http.RoundTripper) http.RoundTripper
+}
+
+// Call describes a finished call to the cloud
+type Call struct {
+	Method string
+	// Endpoint is the path of the call with resource names replaced, i.e. '/loadbalancers/{name}/instances'
+	Endpoint string
+	// StatusCode is zero when no response was received
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// Option configures the client returned by NewClient
+type Option func(*clientContext)
+
+// WithTimeout sets the deadline of a single call to the cloud, including reading the response,
+// calls are aborted sooner when their context is done
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *clientContext) {
+		if timeout > 0 {
+			c.hc.Timeout = timeout
+		}
+	}
+}
+
+// WithTLS sets TLS configuration of connections to the cloud
+func WithTLS(config *tls.Config) Option {
+	return func(c *clientContext) {
+		if config != nil {
+			c.transport.TLSClientConfig = config
+		}
+	}
+}
+
+// WithProxy routes calls to the cloud through given proxy, credentials are taken from the url user info,
+// proxy environment variables are used otherwise
+func WithProxy(proxy *url.URL) Option {
+	return func(c *clientContext) {
+		if proxy != nil {
+			c.transport.Proxy = http.ProxyURL(proxy)
+		}
+	}
+}
+
+// WithHeaders adds static headers to every call, they cannot override headers set by the client
+func WithHeaders(headers http.Header) Option {
+	return func(c *clientContext) {
+		c.headers = headers.Clone()
+	}
+}
+
+// WithUserAgent sets User-Agent header of every call
+func WithUserAgent(userAgent string) Option {
+	return func(c *clientContext) {
+		if userAgent != "" {
+			c.userAgent = userAgent
+		}
+	}
+}
+
+// WithObserver registers function called after every call to the cloud
+func WithObserver(observe func(Call)) Option {
+	return func(c *clientContext) {
+		c.observers = append(c.observers, observe)
+	}
+}
+
+// WithLogger sets the logger calls are logged by, the standard logger is used by default
+func WithLogger(logger *log.Logger) Option {
+	return func(c *clientContext) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
 }
 
 // NewClient returns new instance of Clienter
-func NewClient(cloudHost *url.URL, username string) Clienter {
+func NewClient(cloudHost *url.URL, username string, opts ...Option) Clienter {
 	c := clientContext{
-		scheme:   cloudHost.Scheme,
-		hostname: cloudHost.Host,
-		username: username,
-		hc: http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:    10,
-				IdleConnTimeout: 30 * time.Second,
-			},
+		scheme:    cloudHost.Scheme,
+		hostname:  cloudHost.Host,
+		username:  username,
+		userAgent: DefaultUserAgent,
+		logger:    log.StandardLogger(),
+		hc:        http.Client{Timeout: 30 * time.Second},
+		transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			MaxIdleConns:    10,
+			IdleConnTimeout: 30 * time.Second,
 		},
 	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	c.hc.Transport = c.transport
+	if c.wrap != nil {
+		c.hc.Transport = c.wrap(c.transport)
+	}
 	return &c
 }
 
+func (c *clientContext) ListLoadBalancers(ctx context.Context) ([]LoadBalancer, error) {
+	res, err := c.do(ctx, http.MethodGet, getLoadBalancers, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		var lbs []LoadBalancer
+		if err := json.NewDecoder(res.Body).Decode(&lbs); err != nil {
+			return lbs, err
+		}
+		return lbs, nil
+	case http.StatusForbidden:
+		return nil, ErrForbidden
+	default:
+		return nil, errors.Wrapf(ErrUnknown, "http status code: '%d'", res.StatusCode)
+	}
+}
+
+func (c *clientContext) GetLoadBalancer(ctx context.Context, name string) (LoadBalancer, error) {
+	res, err := c.do(ctx, http.MethodGet, getLoadBalancer, nil, name)
+	if err != nil {
+		return LoadBalancer{}, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		var lb LoadBalancer
+		if err := json.NewDecoder(res.Body).Decode(&lb); err != nil {
+			return lb, err
+		}
+		return lb, nil
+	case http.StatusForbidden:
+		return LoadBalancer{}, ErrForbidden
+	case http.StatusNotFound:
+		return LoadBalancer{}, ErrNotFound
+	default:
+		return LoadBalancer{}, errors.Wrapf(ErrUnknown, "http status code: '%d'", res.StatusCode)
+	}
+}
+
 func (c *clientContext) CreateLoadBalancer(ctx context.Context, name string) (LoadBalancer, error) {
 	res, err := c.do(ctx, http.MethodPut, putLoadBalancers, []byte("{}"), name)
 	if err != nil {
@@ -80,6 +234,25 @@ func (c *clientContext) CreateLoadBalancer(ctx context.Context, name string) (Lo
 	}
 }
 
+func (c *clientContext) DeleteLoadBalancer(ctx context.Context, name string) error {
+	res, err := c.do(ctx, http.MethodDelete, deleteLoadBalancers, nil, name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	switch res.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return errors.Wrapf(ErrUnknown, "http status code: '%d'", res.StatusCode)
+	}
+}
+
 func (c *clientContext) GetInstances(ctx context.Context, lbName string) ([]Instance, error) {
 	res, err := c.do(ctx, http.MethodGet, getLoadBalancersInstances, nil, lbName)
 	if err != nil {
@@ -158,22 +331,34 @@ func (c *clientContext) do(ctx context.Context, method string, path resourceURI,
 		err error
 	)
 	if strings.ToUpper(method) == http.MethodGet || body == nil {
-		req, err = http.NewRequest(method, url.String(), nil)
+		req, err = http.NewRequestWithContext(ctx, method, url.String(), nil)
 	} else {
 		b, err := json.Marshal(&body)
 		if err != nil {
 			return nil, err
 		}
-		req, err = http.NewRequest(method, url.String(), bytes.NewBuffer(b))
+		req, err = http.NewRequestWithContext(ctx, method, url.String(), bytes.NewBuffer(b))
+		if err != nil {
+			return nil, err
+		}
 		req.Header.Add("Content-Type", "application/json")
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Authorization", c.username)
-	req.WithContext(ctx)
+	for key, values := range c.headers {
+		if req.Header.Get(key) != "" {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", c.username)
+	req.Header.Set("User-Agent", c.userAgent)
+	propagate(req)
 
 	now := time.Now()
 	res, err := c.hc.Do(req)
@@ -184,6 +369,17 @@ func (c *clientContext) do(ctx context.Context, method string, path resourceURI,
 		"method":       method,
 		"url":          url.String(),
 	}
-	log.WithContext(ctx).WithFields(f).Trace("http call")
+	if id := RequestID(ctx); id != "" {
+		f["request_id"] = id
+	}
+	c.logger.WithContext(ctx).WithFields(f).Trace("http call")
+
+	call := Call{Method: method, Endpoint: path.endpoint(), Duration: diff, Err: err}
+	if res != nil {
+		call.StatusCode = res.StatusCode
+	}
+	for _, observe := range c.observers {
+		observe(call)
+	}
 	return res, err
 }
@@ -0,0 +1,169 @@
+package remitly_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly/remitlytest"
+)
+
+// hungServer returns a fake cloud never responding within the test
+func hungServer(t *testing.T) *remitlytest.Server {
+	srv := remitlytest.NewServer()
+	srv.Inject(remitlytest.Fault{Latency: time.Minute})
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientContext(t *testing.T) {
+	t.Run("should abort call when context is cancelled", func(t *testing.T) {
+		// arrange
+		srv := hungServer(t)
+		rc := srv.Client("user")
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+
+		// act
+		_, err := rc.ListLoadBalancers(ctx)
+
+		// assert
+		assert.True(t, errors.Is(err, context.Canceled), err)
+		assert.True(t, time.Since(start) < 5*time.Second)
+	})
+
+	t.Run("should abort call when context deadline exceeds", func(t *testing.T) {
+		// arrange
+		srv := hungServer(t)
+		rc := srv.Client("user")
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+
+		// act
+		_, err := rc.CreateInstance(ctx, "lb_1", "1.0.0")
+
+		// assert
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+		assert.True(t, time.Since(start) < 5*time.Second)
+	})
+
+	t.Run("should abort call exceeding configured timeout", func(t *testing.T) {
+		// arrange
+		srv := hungServer(t)
+		u, _ := url.Parse(srv.URL)
+		rc := remitly.NewClient(u, "user", remitly.WithTimeout(100*time.Millisecond))
+		start := time.Now()
+
+		// act
+		_, err := rc.GetInstances(context.Background(), "lb_1")
+
+		// assert
+		assert.Error(t, err)
+		assert.True(t, time.Since(start) < 5*time.Second)
+	})
+}
+
+func TestClientHeaders(t *testing.T) {
+	t.Run("should send static headers and user agent without overriding authorization", func(t *testing.T) {
+		// arrange
+		var got http.Header
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Clone()
+			_, _ = w.Write([]byte("[]"))
+		}))
+		defer srv.Close()
+		u, _ := url.Parse(srv.URL)
+		headers := http.Header{"X-Tenant-Id": {"tenant_1"}, "Authorization": {"someone-else"}}
+		rc := remitly.NewClient(u, "user", remitly.WithHeaders(headers), remitly.WithUserAgent("remitly-cli/1.0.0"))
+
+		// act
+		_, err := rc.ListLoadBalancers(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "tenant_1", got.Get("X-Tenant-Id"))
+		assert.Equal(t, []string{"user"}, got.Values("Authorization"))
+		assert.Equal(t, "remitly-cli/1.0.0", got.Get("User-Agent"))
+	})
+}
+
+func TestClientCorrelation(t *testing.T) {
+	t.Run("should propagate request id and traceparent of the context", func(t *testing.T) {
+		// arrange
+		var got http.Header
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Clone()
+			_, _ = w.Write([]byte("[]"))
+		}))
+		defer srv.Close()
+		u, _ := url.Parse(srv.URL)
+		rc := remitly.NewClient(u, "user")
+		const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		ctx := remitly.WithTraceParent(remitly.WithRequestID(context.Background(), "deployment_1"), traceParent)
+
+		// act
+		_, err := rc.ListLoadBalancers(ctx)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "deployment_1", got.Get(remitly.RequestIDHeader))
+		assert.Equal(t, traceParent, got.Get(remitly.TraceParentHeader))
+	})
+}
+
+func TestClientObserver(t *testing.T) {
+	t.Run("should report calls by endpoint and status", func(t *testing.T) {
+		// arrange
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+		u, _ := url.Parse(srv.URL)
+		var calls []remitly.Call
+		rc := remitly.NewClient(u, "user", remitly.WithObserver(func(call remitly.Call) { calls = append(calls, call) }))
+
+		// act
+		_, _ = rc.CreateLoadBalancer(context.Background(), "lb_1")
+		_ = rc.DeleteInstance(context.Background(), "lb_1", "ins_404")
+
+		// assert
+		assert.Len(t, calls, 2)
+		assert.Equal(t, http.MethodPut, calls[0].Method)
+		assert.Equal(t, "/loadbalancers/{name}", calls[0].Endpoint)
+		assert.Equal(t, http.MethodDelete, calls[1].Method)
+		assert.Equal(t, "/loadbalancers/{name}/instances/{id}", calls[1].Endpoint)
+		assert.Equal(t, http.StatusNotFound, calls[1].StatusCode)
+	})
+}
+
+func TestClientProxy(t *testing.T) {
+	t.Run("should call cloud through authenticated proxy", func(t *testing.T) {
+		// arrange
+		var host, credentials string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, credentials = r.URL.Host, r.Header.Get("Proxy-Authorization")
+			_, _ = w.Write([]byte("[]"))
+		}))
+		defer proxy.Close()
+		proxyURL, _ := url.Parse(proxy.URL)
+		proxyURL.User = url.UserPassword("proxy-user", "proxy-password")
+		u, _ := url.Parse("http://cloud.remitly.io/")
+		rc := remitly.NewClient(u, "user", remitly.WithProxy(proxyURL))
+
+		// act
+		_, err := rc.ListLoadBalancers(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "cloud.remitly.io", host)
+		assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("proxy-user:proxy-password")), credentials)
+	})
+}
@@ -0,0 +1,127 @@
+package remitly
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	getLock    = resourceURI("/locks/%s")
+	putLock    = resourceURI("/locks/%s")
+	deleteLock = resourceURI("/locks/%s")
+)
+
+var ErrLocked = errors.New("lock is held by another owner")
+
+// Lock guards the name against concurrent use, it is released by its owner or once it expires
+type Lock struct {
+	Name         string    `json:"name"`
+	Owner        string    `json:"owner"`
+	DeploymentID string    `json:"deploymentId,omitempty"`
+	AcquiredAt   time.Time `json:"acquiredAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// Expired returns true when the lock expired at given time
+func (l Lock) Expired(now time.Time) bool {
+	return !l.ExpiresAt.After(now)
+}
+
+type AcquireLockParams struct {
+	Owner        string `json:"owner"`
+	DeploymentID string `json:"deploymentId,omitempty"`
+	TTLSeconds   int64  `json:"ttlSeconds"`
+}
+
+type ReleaseLockParams struct {
+	Owner string `json:"owner"`
+	// DeploymentID releases the lock only when held by given deployment, locks of the owner are released when empty
+	DeploymentID string `json:"deploymentId,omitempty"`
+	// Force releases the lock regardless of its owner
+	Force bool `json:"force"`
+}
+
+// Locker is implemented by clients of clouds serving lock endpoints
+type Locker interface {
+	// AcquireLock acquires the lock or extends it when held by the same deployment of the same owner,
+	// returns the current lock and ErrLocked when it is held by another deployment
+	AcquireLock(ctx context.Context, name string, p AcquireLockParams) (Lock, error)
+	// GetLock returns the lock by name, ErrNotFound when it is not held
+	GetLock(ctx context.Context, name string) (Lock, error)
+	// ReleaseLock releases the lock by name, ErrLocked when it is held by another owner or deployment and not forced
+	ReleaseLock(ctx context.Context, name string, p ReleaseLockParams) error
+}
+
+func (c *clientContext) AcquireLock(ctx context.Context, name string, p AcquireLockParams) (Lock, error) {
+	res, err := c.do(ctx, http.MethodPut, putLock, p, name)
+	if err != nil {
+		return Lock{}, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusConflict:
+		var lock Lock
+		if err := json.NewDecoder(res.Body).Decode(&lock); err != nil {
+			return lock, err
+		}
+		if res.StatusCode == http.StatusConflict {
+			return lock, ErrLocked
+		}
+		return lock, nil
+	case http.StatusForbidden:
+		return Lock{}, ErrForbidden
+	case http.StatusNotFound:
+		return Lock{}, ErrNotFound
+	default:
+		return Lock{}, errors.Wrapf(ErrUnknown, "http status code: '%d'", res.StatusCode)
+	}
+}
+
+func (c *clientContext) GetLock(ctx context.Context, name string) (Lock, error) {
+	res, err := c.do(ctx, http.MethodGet, getLock, nil, name)
+	if err != nil {
+		return Lock{}, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		var lock Lock
+		if err := json.NewDecoder(res.Body).Decode(&lock); err != nil {
+			return lock, err
+		}
+		return lock, nil
+	case http.StatusForbidden:
+		return Lock{}, ErrForbidden
+	case http.StatusNotFound:
+		return Lock{}, ErrNotFound
+	default:
+		return Lock{}, errors.Wrapf(ErrUnknown, "http status code: '%d'", res.StatusCode)
+	}
+}
+
+func (c *clientContext) ReleaseLock(ctx context.Context, name string, p ReleaseLockParams) error {
+	res, err := c.do(ctx, http.MethodDelete, deleteLock, p, name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	switch res.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusConflict:
+		return ErrLocked
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return errors.Wrapf(ErrUnknown, "http status code: '%d'", res.StatusCode)
+	}
+}
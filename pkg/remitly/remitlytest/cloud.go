@@ -0,0 +1,391 @@
+package remitlytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+type Option func(*Cloud)
+
+// WithClock sets the clock used to compute instance state transitions, real time by default
+func WithClock(clock Clock) Option {
+	return func(c *Cloud) { c.clock = clock }
+}
+
+// WithBootTime sets how long created instances stay provisioning
+func WithBootTime(d time.Duration) Option {
+	return func(c *Cloud) { c.bootTime = d }
+}
+
+// WithFailureRate sets the probability, from 0 to 1, of a created instance becoming unhealthy
+func WithFailureRate(rate float64) Option {
+	return func(c *Cloud) { c.failureRate = rate }
+}
+
+// WithSeed seeds the random source used with failure rate
+func WithSeed(seed int64) Option {
+	return func(c *Cloud) { c.rand = rand.New(rand.NewSource(seed)) }
+}
+
+// WithUsers restricts access to given usernames, other ones are forbidden
+func WithUsers(usernames ...string) Option {
+	return func(c *Cloud) {
+		for _, username := range usernames {
+			c.users[username] = true
+		}
+	}
+}
+
+// Cloud is an in-memory implementation of the remitly cloud API
+type Cloud struct {
+	mu            sync.Mutex
+	clock         Clock
+	bootTime      time.Duration
+	failureRate   float64
+	rand          *rand.Rand
+	users         map[string]bool
+	unhealthy     map[string]bool
+	loadBalancers map[string]*loadBalancer
+	locks         map[string]remitly.Lock
+	faults        []*Fault
+	sequence      int
+}
+
+type loadBalancer struct {
+	name      string
+	instances []*instance
+}
+
+type instance struct {
+	id      string
+	version string
+	created time.Time
+	failing bool
+}
+
+// NewCloud returns empty Cloud
+func NewCloud(opts ...Option) *Cloud {
+	c := Cloud{
+		clock:         realClock{},
+		bootTime:      5 * time.Second,
+		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
+		users:         make(map[string]bool),
+		unhealthy:     make(map[string]bool),
+		loadBalancers: make(map[string]*loadBalancer),
+		locks:         make(map[string]remitly.Lock),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c
+}
+
+// Inject registers a fault, faults are matched in order of registration
+func (c *Cloud) Inject(f Fault) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.faults = append(c.faults, &f)
+}
+
+// ClearFaults removes all registered faults
+func (c *Cloud) ClearFaults() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.faults = nil
+}
+
+// MarkUnhealthy makes all instances of given version unhealthy once booted
+func (c *Cloud) MarkUnhealthy(version string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unhealthy[version] = true
+}
+
+// AddLoadBalancer creates load balancer with given name
+func (c *Cloud) AddLoadBalancer(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.createLoadBalancer(name)
+}
+
+// AddInstance creates an already booted instance of given version
+func (c *Cloud) AddInstance(lb, version string) remitly.Instance {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ins := c.createInstance(c.createLoadBalancer(lb), version)
+	ins.created = c.clock.Now().Add(-c.bootTime)
+	return c.view(ins)
+}
+
+// LoadBalancers returns sorted names of existing load balancers
+func (c *Cloud) LoadBalancers() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, 0, len(c.loadBalancers))
+	for name := range c.loadBalancers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Instances returns instances of given load balancer, false when it does not exist
+func (c *Cloud) Instances(lb string) ([]remitly.Instance, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, exists := c.loadBalancers[lb]
+	if !exists {
+		return nil, false
+	}
+	return c.views(l), true
+}
+
+// Lock returns the lock held under given name, false when it is not held or expired
+func (c *Cloud) Lock(name string) (remitly.Lock, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lock(name)
+}
+
+func (c *Cloud) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f := c.fault(r); f != nil {
+		// consuming the body lets the server notice a client giving up on the request
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+		select {
+		case <-time.After(f.Latency):
+		case <-r.Context().Done():
+			return
+		}
+		if f.StatusCode != 0 {
+			w.WriteHeader(f.StatusCode)
+			return
+		}
+	}
+
+	if username := r.Header.Get("Authorization"); username == "" || (len(c.users) > 0 && !c.users[username]) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if segments[0] == "locks" && len(segments) == 2 {
+		c.serveLock(w, r, segments[1])
+		return
+	}
+	if segments[0] != "loadbalancers" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		names := make([]string, 0, len(c.loadBalancers))
+		for name := range c.loadBalancers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lbs := make([]remitly.LoadBalancer, 0, len(names))
+		for _, name := range names {
+			lbs = append(lbs, remitly.LoadBalancer{Name: name})
+		}
+		respond(w, http.StatusOK, lbs)
+	case len(segments) == 2 && r.Method == http.MethodGet:
+		if _, exists := c.loadBalancers[segments[1]]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		respond(w, http.StatusOK, remitly.LoadBalancer{Name: segments[1]})
+	case len(segments) == 2 && r.Method == http.MethodPut:
+		c.createLoadBalancer(segments[1])
+		respond(w, http.StatusCreated, remitly.LoadBalancer{Name: segments[1]})
+	case len(segments) == 2 && r.Method == http.MethodDelete:
+		lb, exists := c.loadBalancers[segments[1]]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if len(lb.instances) > 0 {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		delete(c.loadBalancers, segments[1])
+		w.WriteHeader(http.StatusNoContent)
+	case len(segments) == 3 && segments[2] == "instances":
+		lb, exists := c.loadBalancers[segments[1]]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			respond(w, http.StatusOK, c.views(lb))
+		case http.MethodPost:
+			var p remitly.CreateInstanceParams
+			if err := json.NewDecoder(r.Body).Decode(&p); err != nil || p.Version == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			respond(w, http.StatusCreated, c.view(c.createInstance(lb, p.Version)))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	case len(segments) == 4 && segments[2] == "instances" && r.Method == http.MethodDelete:
+		lb, exists := c.loadBalancers[segments[1]]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for i, ins := range lb.instances {
+			if ins.id == segments[3] {
+				lb.instances = append(lb.instances[:i], lb.instances[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (c *Cloud) serveLock(w http.ResponseWriter, r *http.Request, name string) {
+	current, held := c.lock(name)
+	switch r.Method {
+	case http.MethodGet:
+		if !held {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		respond(w, http.StatusOK, current)
+	case http.MethodPut:
+		var p remitly.AcquireLockParams
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil || p.Owner == "" || p.TTLSeconds <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// the lock is re-entrant for the deployment holding it only, the same owner may run several deployments
+		if held && (current.Owner != p.Owner || current.DeploymentID != p.DeploymentID) {
+			respond(w, http.StatusConflict, current)
+			return
+		}
+		now := c.clock.Now()
+		lock := remitly.Lock{
+			Name:         name,
+			Owner:        p.Owner,
+			DeploymentID: p.DeploymentID,
+			AcquiredAt:   now,
+			ExpiresAt:    now.Add(time.Duration(p.TTLSeconds) * time.Second),
+		}
+		if held {
+			lock.AcquiredAt = current.AcquiredAt
+		}
+		c.locks[name] = lock
+		respond(w, http.StatusOK, lock)
+	case http.MethodDelete:
+		var p remitly.ReleaseLockParams
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !held {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if (current.Owner != p.Owner || (p.DeploymentID != "" && current.DeploymentID != p.DeploymentID)) && !p.Force {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		delete(c.locks, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// lock returns the lock held under given name, expired locks are removed
+func (c *Cloud) lock(name string) (remitly.Lock, bool) {
+	lock, exists := c.locks[name]
+	if exists && lock.Expired(c.clock.Now()) {
+		delete(c.locks, name)
+		return remitly.Lock{}, false
+	}
+	return lock, exists
+}
+
+// fault returns the first registered fault matching the request
+func (c *Cloud) fault(r *http.Request) *Fault {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, f := range c.faults {
+		if !f.matches(r) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				c.faults = append(c.faults[:i:i], c.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (c *Cloud) createLoadBalancer(name string) *loadBalancer {
+	lb, exists := c.loadBalancers[name]
+	if !exists {
+		lb = &loadBalancer{name: name}
+		c.loadBalancers[name] = lb
+	}
+	return lb
+}
+
+func (c *Cloud) createInstance(lb *loadBalancer, version string) *instance {
+	c.sequence++
+	ins := instance{
+		id:      fmt.Sprintf("ins_%d", c.sequence),
+		version: version,
+		created: c.clock.Now(),
+		failing: c.failureRate > 0 && c.rand.Float64() < c.failureRate,
+	}
+	lb.instances = append(lb.instances, &ins)
+	return &ins
+}
+
+func (c *Cloud) views(lb *loadBalancer) []remitly.Instance {
+	instances := make([]remitly.Instance, 0, len(lb.instances))
+	for _, ins := range lb.instances {
+		instances = append(instances, c.view(ins))
+	}
+	return instances
+}
+
+// view computes the state of the instance at the current time of the clock
+func (c *Cloud) view(ins *instance) remitly.Instance {
+	state := remitly.StateHealthy
+	if c.clock.Now().Sub(ins.created) < c.bootTime {
+		state = remitly.StateProvisioning
+	} else if ins.failing || c.unhealthy[ins.version] {
+		state = remitly.StateUnhealthy
+	}
+	return remitly.Instance{ID: ins.id, Status: state, Version: ins.version}
+}
+
+func respond(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,28 @@
+package remitlytest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fault describes an error injected into the requests handled by the Cloud
+type Fault struct {
+	// Method of affected requests, empty matches every method
+	Method string
+	// PathPrefix of affected requests, empty matches every path
+	PathPrefix string
+	// Latency added before the request is handled
+	Latency time.Duration
+	// StatusCode returned instead of handling the request, zero handles the request as usual
+	StatusCode int
+	// Times is the number of affected requests, zero affects all of them
+	Times int
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, r.Method) {
+		return false
+	}
+	return strings.HasPrefix(r.URL.Path, f.PathPrefix)
+}
@@ -0,0 +1,36 @@
+// Package remitlytest provides a fake remitly cloud for tests,
+// in the spirit of net/http/httptest.
+package remitlytest
+
+import (
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+)
+
+// Server is a Cloud served over a local HTTP listener
+type Server struct {
+	*Cloud
+	URL string
+
+	srv *httptest.Server
+}
+
+// NewServer starts and returns new Server, callers should Close it when finished
+func NewServer(opts ...Option) *Server {
+	c := NewCloud(opts...)
+	srv := httptest.NewServer(c)
+	return &Server{Cloud: c, URL: srv.URL, srv: srv}
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns remitly client pointed at the server
+func (s *Server) Client(username string) remitly.Clienter {
+	u, _ := url.Parse(s.URL)
+	return remitly.NewClient(u, username)
+}
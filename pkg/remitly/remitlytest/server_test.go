@@ -0,0 +1,185 @@
+package remitlytest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/pkg/remitly"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly/remitlytest"
+)
+
+func TestServer(t *testing.T) {
+	t.Run("should transition instances from provisioning to healthy", func(t *testing.T) {
+		// arrange
+		clock := remitlytest.NewManualClock(time.Now())
+		srv := remitlytest.NewServer(remitlytest.WithClock(clock), remitlytest.WithBootTime(10*time.Second))
+		defer srv.Close()
+		rc := srv.Client("user")
+		ctx := context.Background()
+
+		// act
+		_, err := rc.CreateLoadBalancer(ctx, "lb_1")
+		assert.NoError(t, err)
+		created, err := rc.CreateInstance(ctx, "lb_1", "1")
+		assert.NoError(t, err)
+
+		// assert
+		assert.Equal(t, remitly.StateProvisioning, created.Status)
+		clock.Advance(10 * time.Second)
+		instances, err := rc.GetInstances(ctx, "lb_1")
+		assert.NoError(t, err)
+		assert.Len(t, instances, 1)
+		assert.Equal(t, created.ID, instances[0].ID)
+		assert.Equal(t, remitly.StateHealthy, instances[0].Status)
+	})
+
+	t.Run("should transition instances of unhealthy version to unhealthy", func(t *testing.T) {
+		// arrange
+		clock := remitlytest.NewManualClock(time.Now())
+		srv := remitlytest.NewServer(remitlytest.WithClock(clock), remitlytest.WithBootTime(time.Second))
+		defer srv.Close()
+		srv.AddLoadBalancer("lb_1")
+		srv.MarkUnhealthy("2")
+		rc := srv.Client("user")
+
+		// act
+		_, err := rc.CreateInstance(context.Background(), "lb_1", "2")
+		clock.Advance(time.Second)
+
+		// assert
+		assert.NoError(t, err)
+		instances, _ := srv.Instances("lb_1")
+		assert.Equal(t, remitly.StateUnhealthy, instances[0].Status)
+	})
+
+	t.Run("should map missing load balancer to not found", func(t *testing.T) {
+		// arrange
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+
+		// act
+		_, err := srv.Client("user").GetInstances(context.Background(), "missing")
+
+		// assert
+		assert.Equal(t, remitly.ErrNotFound, err)
+	})
+
+	t.Run("should refuse to delete load balancer with instances", func(t *testing.T) {
+		// arrange
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+		ins := srv.AddInstance("lb_1", "1")
+		rc := srv.Client("user")
+
+		// act
+		errNotEmpty := rc.DeleteLoadBalancer(context.Background(), "lb_1")
+		errInstance := rc.DeleteInstance(context.Background(), "lb_1", ins.ID)
+		errEmpty := rc.DeleteLoadBalancer(context.Background(), "lb_1")
+
+		// assert
+		assert.True(t, errors.Is(errNotEmpty, remitly.ErrUnknown))
+		assert.NoError(t, errInstance)
+		assert.NoError(t, errEmpty)
+		assert.Empty(t, srv.LoadBalancers())
+	})
+
+	t.Run("should forbid unknown users", func(t *testing.T) {
+		// arrange
+		srv := remitlytest.NewServer(remitlytest.WithUsers("admin"))
+		defer srv.Close()
+
+		// act
+		_, err := srv.Client("intruder").ListLoadBalancers(context.Background())
+
+		// assert
+		assert.Equal(t, remitly.ErrForbidden, err)
+	})
+
+	t.Run("should inject status code faults given number of times", func(t *testing.T) {
+		// arrange
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+		srv.AddLoadBalancer("lb_1")
+		srv.Inject(remitlytest.Fault{Method: http.MethodPost, StatusCode: http.StatusServiceUnavailable, Times: 1})
+		rc := srv.Client("user")
+
+		// act
+		_, errFirst := rc.CreateInstance(context.Background(), "lb_1", "1")
+		_, errSecond := rc.CreateInstance(context.Background(), "lb_1", "1")
+
+		// assert
+		assert.True(t, errors.Is(errFirst, remitly.ErrUnknown))
+		assert.NoError(t, errSecond)
+	})
+
+	t.Run("should inject forbidden faults", func(t *testing.T) {
+		// arrange
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+		srv.AddLoadBalancer("lb_1")
+		srv.Inject(remitlytest.Fault{PathPrefix: "/loadbalancers/lb_1", StatusCode: http.StatusForbidden})
+
+		// act
+		_, err := srv.Client("user").GetInstances(context.Background(), "lb_1")
+
+		// assert
+		assert.Equal(t, remitly.ErrForbidden, err)
+	})
+
+	t.Run("should inject latency", func(t *testing.T) {
+		// arrange
+		srv := remitlytest.NewServer()
+		defer srv.Close()
+		srv.Inject(remitlytest.Fault{Latency: 200 * time.Millisecond})
+
+		// act
+		start := time.Now()
+		_, err := srv.Client("user").ListLoadBalancers(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, time.Since(start) >= 200*time.Millisecond)
+	})
+
+	t.Run("should hold lock until released or expired", func(t *testing.T) {
+		// arrange
+		clock := remitlytest.NewManualClock(time.Now())
+		srv := remitlytest.NewServer(remitlytest.WithClock(clock))
+		defer srv.Close()
+		locker := srv.Client("user").(remitly.Locker)
+		ctx := context.Background()
+
+		// act
+		acquired, err := locker.AcquireLock(ctx, "app", remitly.AcquireLockParams{Owner: "alice", DeploymentID: "d1", TTLSeconds: 60})
+		assert.NoError(t, err)
+		held, blocked := locker.AcquireLock(ctx, "app", remitly.AcquireLockParams{Owner: "bob", TTLSeconds: 60})
+		_, concurrent := locker.AcquireLock(ctx, "app", remitly.AcquireLockParams{Owner: "alice", DeploymentID: "d2", TTLSeconds: 60})
+		_, extended := locker.AcquireLock(ctx, "app", remitly.AcquireLockParams{Owner: "alice", DeploymentID: "d1", TTLSeconds: 60})
+		refused := locker.ReleaseLock(ctx, "app", remitly.ReleaseLockParams{Owner: "bob"})
+		other := locker.ReleaseLock(ctx, "app", remitly.ReleaseLockParams{Owner: "alice", DeploymentID: "d2"})
+		clock.Advance(time.Minute)
+		_, expired := locker.GetLock(ctx, "app")
+		_, reacquired := locker.AcquireLock(ctx, "app", remitly.AcquireLockParams{Owner: "bob", TTLSeconds: 60})
+		forced := locker.ReleaseLock(ctx, "app", remitly.ReleaseLockParams{Owner: "alice", Force: true})
+
+		// assert
+		assert.Equal(t, "alice", acquired.Owner)
+		assert.Equal(t, acquired.AcquiredAt.Add(time.Minute).Unix(), acquired.ExpiresAt.Unix())
+		assert.Equal(t, remitly.ErrLocked, blocked)
+		assert.Equal(t, "d1", held.DeploymentID)
+		assert.Equal(t, remitly.ErrLocked, concurrent)
+		assert.NoError(t, extended)
+		assert.Equal(t, remitly.ErrLocked, refused)
+		assert.Equal(t, remitly.ErrLocked, other)
+		assert.Equal(t, remitly.ErrNotFound, expired)
+		assert.NoError(t, reacquired)
+		assert.NoError(t, forced)
+		_, exists := srv.Lock("app")
+		assert.False(t, exists)
+	})
+}
@@ -0,0 +1,52 @@
+package remitly
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	// RequestIDHeader correlates all calls made on behalf of a single operation, e.g. a deployment
+	RequestIDHeader = "X-Request-ID"
+	// TraceParentHeader propagates W3C trace context of the calls
+	TraceParentHeader = "traceparent"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	traceParentKey
+)
+
+// WithRequestID returns context whose calls carry given request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID carried by the context, empty when there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithTraceParent returns context whose calls carry given W3C traceparent
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentKey, traceParent)
+}
+
+// TraceParent returns the W3C traceparent carried by the context, empty when there is none
+func TraceParent(ctx context.Context) string {
+	tp, _ := ctx.Value(traceParentKey).(string)
+	return tp
+}
+
+// propagate sets correlation headers of the request from its context
+func propagate(req *http.Request) {
+	if id := RequestID(req.Context()); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+	if tp := TraceParent(req.Context()); tp != "" {
+		req.Header.Set(TraceParentHeader, tp)
+	}
+}
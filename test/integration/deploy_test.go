@@ -0,0 +1,941 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/remitly-cli/internal/exitcode"
+	"github.com/mazxaxz/remitly-cli/internal/webhook"
+	"github.com/mazxaxz/remitly-cli/pkg/remitly/remitlytest"
+)
+
+const loadBalancerName = "app-lb"
+
+// versions returns the number of instances per version of the load balancer
+func versions(t *testing.T, srv *remitlytest.Server) map[string]int {
+	return versionsOf(t, srv, loadBalancerName)
+}
+
+// versionsOf returns the number of instances per version of given load balancer
+func versionsOf(t *testing.T, srv *remitlytest.Server, lbName string) map[string]int {
+	instances, exists := srv.Instances(lbName)
+	if !exists {
+		t.Fatalf("load balancer '%s' does not exist", lbName)
+	}
+	result := make(map[string]int)
+	for _, instance := range instances {
+		result[instance.Version]++
+	}
+	return result
+}
+
+func TestInitializeAndDeploy(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	env := newEnvironment(t)
+
+	// act
+	initialized := env.run(t, "initialize", "-n", env.profile, "--url", srv.URL, "--username", "integration")
+	deployed := env.run(t, "deploy", "-a", "app", "--revision", "1.0.0", "--replica-count", "2", "-o", "json")
+
+	// assert
+	assert.Equal(t, exitcode.Success, initialized.code, initialized.stderr)
+	assert.Equal(t, exitcode.Success, deployed.code, deployed.stderr)
+
+	var summary struct {
+		Code    string   `json:"code"`
+		Created []string `json:"created"`
+		Deleted []string `json:"deleted"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(deployed.stdout), &summary))
+	assert.Equal(t, "success", summary.Code)
+	assert.Len(t, summary.Created, 2)
+	assert.Empty(t, summary.Deleted)
+	assert.Equal(t, map[string]int{"1.0.0": 2}, versions(t, srv))
+}
+
+func TestDeployNewRevision(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	env := newEnvironment(t).withContext(t, srv.URL)
+
+	// act
+	scaled := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0", "--replica-count", "3")
+	again := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0")
+
+	// assert
+	assert.Equal(t, exitcode.Success, scaled.code, scaled.stderr)
+	assert.Equal(t, exitcode.AlreadyDeployed, again.code, again.stderr)
+	assert.Equal(t, map[string]int{"2.0.0": 3}, versions(t, srv))
+}
+
+func TestDeployUnhealthy(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	srv.MarkUnhealthy("2.0.0")
+	env := newEnvironment(t).withContext(t, srv.URL)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0")
+
+	// assert
+	assert.Equal(t, exitcode.Unhealthy, res.code, res.stderr)
+	assert.Equal(t, map[string]int{"1.0.0": 2}, versions(t, srv))
+}
+
+func TestDeployTimeout(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Minute))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	env := newEnvironment(t).withContext(t, srv.URL)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0", "--wait", "3")
+
+	// assert
+	assert.Equal(t, exitcode.Timeout, res.code, res.stderr)
+	assert.Equal(t, map[string]int{"1.0.0": 1}, versions(t, srv))
+}
+
+func TestDeployMidRolloutFailure(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	// the first removal of an old instance fails, the rollback succeeds
+	srv.Inject(remitlytest.Fault{Method: http.MethodDelete, StatusCode: http.StatusInternalServerError, Times: 1})
+	env := newEnvironment(t).withContext(t, srv.URL)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0")
+
+	// assert
+	assert.Equal(t, exitcode.RolledBack, res.code, res.stderr)
+	assert.Equal(t, map[string]int{"1.0.0": 2}, versions(t, srv))
+}
+
+func TestDeployCreateFailure(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	// creation of new instances fails, the rollback has nothing to create
+	srv.Inject(remitlytest.Fault{Method: http.MethodPost, StatusCode: http.StatusInternalServerError})
+	env := newEnvironment(t).withContext(t, srv.URL)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0", "-o", "json")
+
+	// assert
+	assert.Equal(t, exitcode.RolledBack, res.code, res.stderr)
+	var summary struct {
+		RolledBack bool `json:"rolledBack"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(res.stdout), &summary))
+	assert.True(t, summary.RolledBack)
+	assert.Equal(t, map[string]int{"1.0.0": 2}, versions(t, srv))
+}
+
+func TestDeployCreateFailureCause(t *testing.T) {
+	tests := []struct {
+		name      string
+		giveFault remitlytest.Fault
+		giveArgs  []string
+		wantCode  int
+	}{
+		{
+			name:      "should return auth code when creation is forbidden",
+			giveFault: remitlytest.Fault{Method: http.MethodPost, StatusCode: http.StatusForbidden},
+			wantCode:  exitcode.Auth,
+		},
+		{
+			name:      "should return timeout code when creation times out",
+			giveFault: remitlytest.Fault{Method: http.MethodPost, Latency: time.Minute},
+			giveArgs:  []string{"--wait", "2"},
+			wantCode:  exitcode.Timeout,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// arrange
+			srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+			defer srv.Close()
+			srv.AddInstance(loadBalancerName, "1.0.0")
+			srv.Inject(tt.giveFault)
+			env := newEnvironment(t).withContext(t, srv.URL)
+
+			// act
+			res := env.run(t, append([]string{"deploy", "-a", "app", "--revision", "2.0.0"}, tt.giveArgs...)...)
+
+			// assert
+			assert.Equal(t, tt.wantCode, res.code, res.stderr)
+			assert.Equal(t, map[string]int{"1.0.0": 1}, versions(t, srv))
+		})
+	}
+}
+
+func TestDeployRollbackFailure(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	srv.MarkUnhealthy("2.0.0")
+	srv.Inject(remitlytest.Fault{Method: http.MethodDelete, StatusCode: http.StatusInternalServerError})
+	env := newEnvironment(t).withContext(t, srv.URL)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0")
+
+	// assert
+	assert.Equal(t, exitcode.RollbackFailed, res.code, res.stderr)
+	assert.Equal(t, map[string]int{"1.0.0": 1, "2.0.0": 1}, versions(t, srv))
+}
+
+func TestDeployForbidden(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithUsers("someone-else"))
+	defer srv.Close()
+	env := newEnvironment(t).withContext(t, srv.URL)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "1.0.0")
+
+	// assert
+	assert.Equal(t, exitcode.Auth, res.code, res.stderr)
+	assert.Empty(t, srv.LoadBalancers())
+}
+
+func TestDeployWithoutLoadBalancerCreation(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer()
+	defer srv.Close()
+	env := newEnvironment(t).withContext(t, srv.URL)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "1.0.0", "--create-load-balancer", "never")
+
+	// assert
+	assert.Equal(t, exitcode.NotFound, res.code, res.stderr)
+	assert.Empty(t, srv.LoadBalancers())
+}
+
+func TestDeployTraceExport(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	env := newEnvironment(t).withContext(t, srv.URL)
+	file := filepath.Join(env.home, "spans.jsonl")
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "1.0.0", "--replica-count", "1", "-o", "json", "--trace-file", file)
+
+	// assert
+	assert.Equal(t, exitcode.Success, res.code, res.stderr)
+	var summary struct {
+		ID      string `json:"id"`
+		TraceID string `json:"traceId"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(res.stdout), &summary))
+	assert.NotEqual(t, summary.ID, summary.TraceID)
+
+	b, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	var export struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID string `json:"traceId"`
+					Name    string `json:"name"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &export))
+	var names []string
+	for _, span := range export.ResourceSpans[0].ScopeSpans[0].Spans {
+		assert.Equal(t, summary.TraceID, span.TraceID)
+		names = append(names, span.Name)
+	}
+	assert.Equal(t, []string{"snapshot", "deploy", "orchestrate", "deployment"}, names)
+}
+
+func TestDeployMetricsExport(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	srv.MarkUnhealthy("2.0.0")
+	env := newEnvironment(t).withContext(t, srv.URL)
+	file := filepath.Join(env.home, "metrics", "deploy.prom")
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0", "--metrics-file", file)
+
+	// assert
+	assert.Equal(t, exitcode.Unhealthy, res.code, res.stderr)
+	b, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `remitly_deploy_result{app="app",context="integration",code="unhealthy"} 1`)
+	assert.Contains(t, string(b), `remitly_deploy_rolled_back{app="app",context="integration"} 1`)
+	assert.Contains(t, string(b), "# EOF\n")
+}
+
+func TestDeployWebhooks(t *testing.T) {
+	t.Parallel()
+	// arrange
+	var (
+		mu     sync.Mutex
+		events []string
+		valid  = true
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		valid = valid && webhook.Sign("integration-secret", body) == r.Header.Get(webhook.SignatureHeader)
+		var e webhook.Event
+		_ = json.Unmarshal(body, &e)
+		events = append(events, strings.TrimSpace(e.Type+" "+e.Step))
+	}))
+	defer receiver.Close()
+
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	srv.MarkUnhealthy("2.0.0")
+	env := newEnvironment(t).withContext(t, srv.URL, fmt.Sprintf(`    webhooks:
+      - url: %s
+        secret: integration-secret
+`, receiver.URL))
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0")
+
+	// assert
+	assert.Equal(t, exitcode.Unhealthy, res.code, res.stderr)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, valid)
+	assert.Equal(t, []string{
+		"deployment.started",
+		"deployment.step snapshot",
+		"deployment.step deploy",
+		"deployment.step orchestrate",
+		"rollback.started",
+		"rollback.succeeded",
+		"deployment.failed",
+	}, events)
+}
+
+func TestDeployUnresponsiveWebhook(t *testing.T) {
+	t.Parallel()
+	// arrange
+	block := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer receiver.Close()
+	defer close(block)
+
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	env := newEnvironment(t).withContext(t, srv.URL, fmt.Sprintf(`    webhooks:
+      - url: %s
+`, receiver.URL))
+
+	// act
+	start := time.Now()
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0", "--webhook-timeout", "1s")
+	elapsed := time.Since(start)
+
+	// assert
+	assert.Equal(t, exitcode.Success, res.code, res.stderr)
+	assert.Equal(t, map[string]int{"2.0.0": 1}, versions(t, srv))
+	// a single delivery posted in line with the deployment would take 5s of client timeout per attempt
+	assert.True(t, elapsed < 10*time.Second, elapsed)
+	assert.Contains(t, res.stderr, "dropping them")
+}
+
+func TestDeployPreDeployHookFailure(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	env := newEnvironment(t).withContext(t, srv.URL)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0", "--pre-deploy", "exit 1")
+
+	// assert
+	assert.Equal(t, exitcode.HookFailed, res.code, res.stderr)
+	assert.Equal(t, map[string]int{"1.0.0": 1}, versions(t, srv))
+}
+
+func TestDeployPostHealthyHookRollback(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	env := newEnvironment(t)
+	file := filepath.Join(env.home, "hooks.log")
+	env = env.withContext(t, srv.URL, fmt.Sprintf(`    hooks:
+      post_healthy: echo "$REMITLY_HOOK $REMITLY_PREVIOUS_REVISION $REMITLY_REVISION $REMITLY_LOAD_BALANCER" >> %[1]s; exit 1
+      pre_rollback: echo "$REMITLY_HOOK $REMITLY_CODE" >> %[1]s
+      post_deploy: echo "$REMITLY_HOOK" >> %[1]s
+      rollback_on_failure: true
+`, file))
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0")
+
+	// assert
+	assert.Equal(t, exitcode.RolledBack, res.code, res.stderr)
+	assert.Equal(t, map[string]int{"1.0.0": 2}, versions(t, srv))
+	b, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "post-healthy 1.0.0 2.0.0 app-lb\npre-rollback error\n", string(b))
+}
+
+func TestDeployVerificationFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		giveArgs []string
+	}{
+		{
+			name:     "should roll back when command fails",
+			giveArgs: []string{"--verify", "exit 1"},
+		},
+		{
+			name:     "should roll back when command hangs",
+			giveArgs: []string{"--verify", "sleep 60", "--verify-timeout", "1s"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// arrange
+			srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+			defer srv.Close()
+			srv.AddInstance(loadBalancerName, "1.0.0")
+			srv.AddInstance(loadBalancerName, "1.0.0")
+			env := newEnvironment(t).withContext(t, srv.URL)
+			args := []string{"deploy", "-a", "app", "--revision", "2.0.0", "--verify-attempts", "2", "--verify-interval", "100ms"}
+
+			// act
+			res := env.run(t, append(args, tt.giveArgs...)...)
+
+			// assert
+			assert.Equal(t, exitcode.Unhealthy, res.code, res.stderr)
+			assert.Equal(t, map[string]int{"1.0.0": 2}, versions(t, srv))
+		})
+	}
+}
+
+func TestDeployLocked(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	env := newEnvironment(t).withContext(t, srv.URL)
+	host, _ := os.Hostname()
+	held := fmt.Sprintf(`{"owner":"alice@%s","deploymentId":"d1","host":"%s","pid":%d,"acquiredAt":"%s","expiresAt":"%s"}`,
+		host, host, os.Getpid(), time.Now().Format(time.RFC3339), time.Now().Add(time.Hour).Format(time.RFC3339))
+	dir := filepath.Join(env.path, "locks", env.profile)
+	assert.NoError(t, os.MkdirAll(dir, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app.lock"), []byte(held), 0644))
+
+	// act
+	blocked := env.run(t, "deploy", "-a", "app", "--revision", "1.0.0")
+	status := env.run(t, "lock", "status", "-a", "app", "-o", "json")
+	refused := env.run(t, "lock", "release", "-a", "app")
+	released := env.run(t, "lock", "release", "-a", "app", "--force")
+	deployed := env.run(t, "deploy", "-a", "app", "--revision", "1.0.0", "--remote-lock")
+
+	// assert
+	assert.Equal(t, exitcode.Locked, blocked.code, blocked.stderr)
+	assert.Contains(t, blocked.stderr, "alice@"+host+" (deployment d1)")
+	assert.Equal(t, exitcode.Success, status.code, status.stderr)
+	assert.Contains(t, status.stdout, `"owner": "alice@`+host+`"`)
+	assert.Equal(t, exitcode.Locked, refused.code, refused.stderr)
+	assert.Equal(t, exitcode.Success, released.code, released.stderr)
+	assert.Equal(t, exitcode.Success, deployed.code, deployed.stderr)
+	_, remote := srv.Lock("app")
+	assert.False(t, remote)
+	_, err := os.Stat(filepath.Join(dir, "app.lock"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDeployRolloutAbort(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	env := newEnvironment(t).withContext(t, srv.URL)
+	lockFile := filepath.Join(env.path, "locks", env.profile, "app.lock")
+
+	// act
+	idle := env.run(t, "rollout", "pause", "-a", "app")
+	done := make(chan result)
+	go func() { done <- env.run(t, "deploy", "-a", "app", "--revision", "2.0.0") }()
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(lockFile); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	paused := env.run(t, "rollout", "pause", "-a", "app")
+	// the deployment holds for longer than the boot time of its instances
+	time.Sleep(4 * time.Second)
+	held := versions(t, srv)
+	aborted := env.run(t, "rollout", "abort", "-a", "app")
+	res := <-done
+
+	// assert
+	assert.Equal(t, exitcode.Error, idle.code, idle.stderr)
+	assert.Contains(t, idle.stderr, "no deployment of the app is in progress")
+	assert.Equal(t, exitcode.Success, paused.code, paused.stderr)
+	assert.Equal(t, map[string]int{"1.0.0": 1, "2.0.0": 1}, held)
+	assert.Equal(t, exitcode.Success, aborted.code, aborted.stderr)
+	assert.Equal(t, exitcode.Aborted, res.code, res.stderr)
+	assert.Equal(t, map[string]int{"1.0.0": 1}, versions(t, srv))
+}
+
+func TestStatusAndWait(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	env := newEnvironment(t).withContext(t, srv.URL)
+
+	// act
+	timedOut := env.run(t, "wait", "-a", "app", "--revision", "2.0.0", "--timeout", "1s")
+	waited := make(chan result)
+	go func() {
+		waited <- env.run(t, "wait", "-a", "app", "--revision", "2.0.0", "--for", "deployed", "-o", "json")
+	}()
+	deployed := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0")
+	status := env.run(t, "status", "-a", "app", "-o", "json")
+	res := <-waited
+
+	// assert
+	assert.Equal(t, exitcode.WaitTimeout, timedOut.code, timedOut.stderr)
+	assert.Equal(t, exitcode.Success, deployed.code, deployed.stderr)
+	assert.Equal(t, exitcode.Success, res.code, res.stderr)
+	assert.Contains(t, res.stdout, `"met": true`)
+	assert.Equal(t, exitcode.Success, status.code, status.stderr)
+
+	var s struct {
+		Versions []struct {
+			Version string `json:"version"`
+			Healthy int    `json:"healthy"`
+		} `json:"versions"`
+		Rollout        interface{} `json:"rollout"`
+		LastDeployment struct {
+			Revision         string `json:"revision"`
+			PreviousRevision string `json:"previousRevision"`
+			Code             string `json:"code"`
+		} `json:"lastDeployment"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(status.stdout), &s))
+	assert.Len(t, s.Versions, 1)
+	assert.Equal(t, "2.0.0", s.Versions[0].Version)
+	assert.Nil(t, s.Rollout)
+	assert.Equal(t, "2.0.0", s.LastDeployment.Revision)
+	assert.Equal(t, "1.0.0", s.LastDeployment.PreviousRevision)
+	assert.Equal(t, "success", s.LastDeployment.Code)
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer srv.Close()
+	srv.AddInstance(loadBalancerName, "1.0.0")
+	env := newEnvironment(t).withContext(t, srv.URL)
+
+	// act
+	watched := make(chan result)
+	go func() {
+		watched <- env.run(t, "watch", "-a", "app", "--interval", "100ms", "--timeout", "8s", "-o", "jsonl")
+	}()
+	deployed := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0")
+	res := <-watched
+
+	// assert
+	assert.Equal(t, exitcode.Success, deployed.code, deployed.stderr)
+	assert.Equal(t, exitcode.Success, res.code, res.stderr)
+	types := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(res.stdout), "\n") {
+		var e struct {
+			Type    string `json:"type"`
+			Version string `json:"version"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(line), &e), line)
+		types[e.Type+" "+e.Version]++
+	}
+	// the new instance may be found by the first poll already, if deploy was faster
+	assert.Equal(t, 1, types["existing 1.0.0"], res.stdout)
+	assert.Equal(t, 1, types["changed 2.0.0"], res.stdout)
+	assert.Equal(t, 1, types["disappeared 1.0.0"], res.stdout)
+}
+
+func TestDeployRelease(t *testing.T) {
+	const file = `
+apps:
+  - name: web
+    revision: 2.0.0
+    depends_on: [api]
+  - name: api
+    revision: 2.0.0
+    replica_count: 2
+    depends_on: [db]
+  - name: db
+    revision: 2.0.0
+`
+	tests := []struct {
+		name         string
+		giveArgs     []string
+		wantCode     int
+		wantStates   map[string]string
+		wantVersions map[string]map[string]int
+	}{
+		{
+			name:       "should deploy apps in order of their dependencies",
+			wantCode:   exitcode.Success,
+			wantStates: map[string]string{"db": "deployed", "api": "deployed", "web": "deployed"},
+			wantVersions: map[string]map[string]int{
+				"db-lb": {"2.0.0": 1}, "api-lb": {"2.0.0": 2}, "web-lb": {"2.0.0": 1},
+			},
+		},
+		{
+			name:       "should roll back deployed apps when a later one fails",
+			giveArgs:   []string{"--pre-deploy", `test "$REMITLY_APP" != web`},
+			wantCode:   exitcode.HookFailed,
+			wantStates: map[string]string{"db": "reverted", "api": "reverted", "web": "failed"},
+			wantVersions: map[string]map[string]int{
+				"db-lb": {"1.0.0": 1}, "api-lb": {"1.0.0": 1}, "web-lb": {"1.0.0": 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// arrange
+			srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+			defer srv.Close()
+			for _, app := range []string{"db", "api", "web"} {
+				srv.AddInstance(app+"-lb", "1.0.0")
+			}
+			env := newEnvironment(t).withContext(t, srv.URL)
+			release := filepath.Join(env.home, "release.yml")
+			assert.NoError(t, ioutil.WriteFile(release, []byte(file), 0644))
+
+			// act
+			res := env.run(t, append([]string{"deploy", "-f", release, "-o", "json"}, tt.giveArgs...)...)
+
+			// assert
+			assert.Equal(t, tt.wantCode, res.code, res.stderr)
+			var r struct {
+				Apps []struct {
+					Name  string `json:"name"`
+					State string `json:"state"`
+				} `json:"apps"`
+			}
+			assert.NoError(t, json.Unmarshal([]byte(res.stdout), &r), res.stdout)
+			states := make(map[string]string)
+			for _, app := range r.Apps {
+				states[app.Name] = app.State
+			}
+			assert.Equal(t, tt.wantStates, states)
+			for lb, want := range tt.wantVersions {
+				assert.Equal(t, want, versionsOf(t, srv, lb), lb)
+			}
+		})
+	}
+}
+
+func TestDeployContexts(t *testing.T) {
+	tests := []struct {
+		name         string
+		giveArgs     []string
+		wantCode     int
+		wantStates   map[string]string
+		wantVersions map[string]map[string]int
+	}{
+		{
+			name:         "should deploy into every context one after another",
+			wantCode:     exitcode.Success,
+			wantStates:   map[string]string{"eu": "deployed", "us": "deployed"},
+			wantVersions: map[string]map[string]int{"eu": {"2.0.0": 1}, "us": {"2.0.0": 1}},
+		},
+		{
+			name:         "should deploy into the other contexts when one fails",
+			giveArgs:     []string{"--parallel", "--pre-deploy", `test "$REMITLY_CONTEXT" != eu`},
+			wantCode:     exitcode.HookFailed,
+			wantStates:   map[string]string{"eu": "failed", "us": "deployed"},
+			wantVersions: map[string]map[string]int{"eu": {"1.0.0": 1}, "us": {"2.0.0": 1}},
+		},
+		{
+			name:         "should skip the remaining contexts when halting on failure",
+			giveArgs:     []string{"--sequential", "--halt-on-failure", "--pre-deploy", `test "$REMITLY_CONTEXT" != eu`},
+			wantCode:     exitcode.HookFailed,
+			wantStates:   map[string]string{"eu": "failed", "us": "skipped"},
+			wantVersions: map[string]map[string]int{"eu": {"1.0.0": 1}, "us": {"1.0.0": 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// arrange
+			servers := make(map[string]*remitlytest.Server)
+			for _, name := range []string{"eu", "us"} {
+				srv := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+				defer srv.Close()
+				srv.AddInstance(loadBalancerName, "1.0.0")
+				servers[name] = srv
+			}
+			env := newEnvironment(t).withContexts(t, "eu", servers["eu"].URL, "us", servers["us"].URL)
+			args := []string{"deploy", "-a", "app", "--revision", "2.0.0", "--contexts", "eu,us", "-o", "json"}
+
+			// act
+			res := env.run(t, append(args, tt.giveArgs...)...)
+
+			// assert
+			assert.Equal(t, tt.wantCode, res.code, res.stderr)
+			var r struct {
+				TraceID  string `json:"traceId"`
+				Contexts []struct {
+					Context string `json:"context"`
+					State   string `json:"state"`
+					Summary *struct {
+						ID      string `json:"id"`
+						TraceID string `json:"traceId"`
+					} `json:"summary"`
+				} `json:"contexts"`
+			}
+			assert.NoError(t, json.Unmarshal([]byte(res.stdout), &r), res.stdout)
+			states := make(map[string]string)
+			IDs := make(map[string]bool)
+			for _, ctx := range r.Contexts {
+				states[ctx.Context] = ctx.State
+				if ctx.Summary != nil {
+					// deployments into each context have IDs of their own, correlated by the trace ID
+					assert.False(t, IDs[ctx.Summary.ID], ctx.Summary.ID)
+					assert.Equal(t, r.TraceID, ctx.Summary.TraceID)
+					IDs[ctx.Summary.ID] = true
+				}
+			}
+			assert.Equal(t, tt.wantStates, states)
+			for name, want := range tt.wantVersions {
+				assert.Equal(t, want, versions(t, servers[name]), name)
+			}
+		})
+	}
+}
+
+func TestDeployContextsHaltInParallel(t *testing.T) {
+	t.Parallel()
+	// arrange
+	eu := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer eu.Close()
+	eu.AddInstance(loadBalancerName, "1.0.0")
+	us := remitlytest.NewServer(remitlytest.WithBootTime(3 * time.Second))
+	defer us.Close()
+	us.AddInstance(loadBalancerName, "1.0.0")
+	env := newEnvironment(t).withContexts(t, "eu", eu.URL, "us", us.URL)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0", "--contexts", "eu,us", "-o", "json",
+		"--parallel", "--halt-on-failure", "--pre-deploy", `test "$REMITLY_CONTEXT" != eu`)
+
+	// assert
+	assert.Equal(t, exitcode.HookFailed, res.code, res.stderr)
+	assert.NotContains(t, res.stdout, `"state": "deployed"`)
+	assert.Equal(t, map[string]int{"1.0.0": 1}, versions(t, eu))
+	// the rollout in us is either aborted and rolled back, or not started at all
+	assert.Equal(t, map[string]int{"1.0.0": 1}, versions(t, us))
+}
+
+func TestDeployContextsLogFiles(t *testing.T) {
+	t.Parallel()
+	// arrange
+	eu := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer eu.Close()
+	eu.AddInstance(loadBalancerName, "1.0.0")
+	us := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+	defer us.Close()
+	us.AddInstance(loadBalancerName, "1.0.0")
+	env := newEnvironment(t)
+	content := "contexts:\n"
+	for name, url := range map[string]string{"eu": eu.URL, "us": us.URL} {
+		content += fmt.Sprintf("  - name: %s\n    http:\n      url: %s\n      username: integration\n", name, url)
+		content += fmt.Sprintf("    log:\n      format: json\n      file: %s\n", filepath.Join(env.home, name+".log"))
+	}
+	env = env.withContextsFile(t, content)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0", "--contexts", "eu,us", "--parallel")
+
+	// assert
+	assert.Equal(t, exitcode.Success, res.code, res.stderr)
+	for _, name := range []string{"eu", "us"} {
+		logs, err := ioutil.ReadFile(filepath.Join(env.home, name+".log"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(logs), `"msg":"successfully deployed application"`, name)
+		for _, line := range strings.Split(strings.TrimSpace(string(logs)), "\n") {
+			assert.True(t, json.Valid([]byte(line)), line)
+		}
+	}
+	// logs of the deployments are written into the files of their contexts only
+	assert.NotContains(t, res.stderr, "successfully deployed application")
+}
+
+func TestDeployUnknownContext(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer()
+	defer srv.Close()
+	env := newEnvironment(t).withContexts(t, "eu", srv.URL)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0", "--contexts", "eu,us")
+
+	// assert
+	assert.Equal(t, exitcode.Config, res.code, res.stderr)
+	assert.Empty(t, srv.LoadBalancers())
+}
+
+func TestDeployInvalidContext(t *testing.T) {
+	t.Parallel()
+	// arrange
+	srv := remitlytest.NewServer()
+	defer srv.Close()
+	env := newEnvironment(t)
+	content := fmt.Sprintf("contexts:\n  - name: eu\n    http:\n      url: %s\n      username: integration\n", srv.URL)
+	content += "  - name: us\n    http:\n      url: not-a-url\n      username: integration\n"
+	env = env.withContextsFile(t, content)
+
+	// act
+	res := env.run(t, "deploy", "-a", "app", "--revision", "2.0.0", "--contexts", "eu,us")
+
+	// assert
+	assert.Equal(t, exitcode.Config, res.code, res.stderr)
+	assert.Contains(t, res.stderr, "context 'us'")
+	assert.Empty(t, srv.LoadBalancers())
+}
+
+func TestPromote(t *testing.T) {
+	tests := []struct {
+		name         string
+		giveArgs     []string
+		giveStaging  []string
+		wantCode     int
+		wantVersions map[string]int
+		wantRecorded bool
+	}{
+		{
+			name:         "should deploy healthy revision of the source into the target",
+			giveStaging:  []string{"2.0.0", "2.0.0"},
+			wantCode:     exitcode.Success,
+			wantVersions: map[string]int{"2.0.0": 2},
+			wantRecorded: true,
+		},
+		{
+			name:         "should not promote while a rollout is in progress in the source",
+			giveStaging:  []string{"2.0.0", "3.0.0"},
+			wantCode:     exitcode.NothingToPromote,
+			wantVersions: map[string]int{"1.0.0": 1},
+		},
+		{
+			name:         "should not promote without confirmation",
+			giveArgs:     []string{"--confirm"},
+			giveStaging:  []string{"2.0.0"},
+			wantCode:     exitcode.Error,
+			wantVersions: map[string]int{"1.0.0": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// arrange
+			staging := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+			defer staging.Close()
+			for _, version := range tt.giveStaging {
+				staging.AddInstance(loadBalancerName, version)
+			}
+			production := remitlytest.NewServer(remitlytest.WithBootTime(time.Second))
+			defer production.Close()
+			production.AddInstance(loadBalancerName, "1.0.0")
+			env := newEnvironment(t).withContexts(t, "staging", staging.URL, "production", production.URL)
+
+			// act
+			res := env.run(t, append([]string{"promote", "-a", "app", "--from", "staging", "--to", "production"}, tt.giveArgs...)...)
+
+			// assert
+			assert.Equal(t, tt.wantCode, res.code, res.stderr)
+			assert.Equal(t, tt.wantVersions, versions(t, production))
+			for _, name := range []string{"staging", "production"} {
+				b, err := ioutil.ReadFile(filepath.Join(env.path, "history", name+".jsonl"))
+				if !tt.wantRecorded {
+					assert.NotContains(t, string(b), `"kind":"promotion"`, name)
+					continue
+				}
+				assert.NoError(t, err, name)
+				assert.Contains(t, string(b), `"kind":"promotion","context":"`+name+`","app":"app","revision":"2.0.0"`, name)
+				assert.Contains(t, string(b), `"previousRevision":"1.0.0","from":"staging","to":"production"`, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// binary is the path of the compiled CLI, built once for the whole suite
+var binary string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "remitly-integration")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	binary = filepath.Join(dir, "remitly")
+
+	build := exec.Command("go", "build", "-o", binary, "github.com/mazxaxz/remitly-cli/cmd")
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "could not build the CLI:", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type result struct {
+	stdout, stderr string
+	code           int
+}
+
+// environment is an isolated $HOME and $REMITLY_PATH the CLI runs within
+type environment struct {
+	home    string
+	path    string
+	profile string
+}
+
+func newEnvironment(t *testing.T) environment {
+	home := t.TempDir()
+	return environment{home: home, path: filepath.Join(home, ".remitly"), profile: "integration"}
+}
+
+// withContext writes contexts file with a single context pointing at given url,
+// extra is appended to the context as is
+func (e environment) withContext(t *testing.T, url string, extra ...string) environment {
+	content := fmt.Sprintf(`
+contexts:
+  - name: %s
+    http:
+      url: %s
+      username: integration
+%s`, e.profile, url, strings.Join(extra, "\n"))
+	return e.withContextsFile(t, content)
+}
+
+// withContexts writes contexts file with a context per pair of name and url
+func (e environment) withContexts(t *testing.T, nameURLs ...string) environment {
+	content := "contexts:\n"
+	for i := 0; i+1 < len(nameURLs); i += 2 {
+		content += fmt.Sprintf("  - name: %s\n    http:\n      url: %s\n      username: integration\n", nameURLs[i], nameURLs[i+1])
+	}
+	return e.withContextsFile(t, content)
+}
+
+func (e environment) withContextsFile(t *testing.T, content string) environment {
+	if err := os.MkdirAll(e.path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.path, "contexts.yml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// run executes the compiled CLI with given arguments
+func (e environment) run(t *testing.T, args ...string) result {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(binary, args...)
+	cmd.Env = []string{
+		"HOME=" + e.home,
+		"PATH=" + os.Getenv("PATH"),
+		"REMITLY_PATH=" + e.path,
+		"REMITLY_PROFILE=" + e.profile,
+	}
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	r := result{code: 0}
+	if err := cmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatal(err)
+		}
+		r.code = exitErr.ExitCode()
+	}
+	r.stdout, r.stderr = stdout.String(), stderr.String()
+	return r
+}